@@ -1,9 +1,13 @@
 package bar
 
 import (
-	"bytes"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,25 +16,102 @@ var noop = func() {}
 // Bar is a progress bar to be used for displaying task progress
 // via terminal output
 type Bar struct {
+	mu                         sync.Mutex
 	progress, total, width     int
 	start, end                 string
 	complete, head, incomplete string
 	closed                     bool
-	startedAt                  time.Time
+	startedAt, started         time.Time
+	now                        func() time.Time
 	rate                       float64
 	eta                        time.Duration
 	formatString               string
+	prefix                     string
+	suffix                     string
 	format                     []token
 	context                    []*ContextValue
 	callback                   func()
 	output                     Output
 	debug                      bool
+	binaryUnits                bool
+	frame                      int
+	spinner                    []string
+	formatOpts                 formatOpts
+	buf                        []byte
+	minInterval                time.Duration
+	lastDraw                   time.Time
+	smooth                     bool
+	completeColor              Color
+	incompleteColor            Color
+	colorize                   bool
+	tty                        bool
+	thresholds                 []ColorThreshold
+	fitWidth                   bool
+	widthPercent               int
+	fittedWidth                int
+	termWidth                  func() (int, bool)
+	maxWidth                   int
+	ellipsis                   string
+	group                      *Group
+	rateSmoothing              float64
+	rateWindow                 time.Duration
+	window                     rateWindow
+	smoothed                   rateAverage
+	paused                     bool
+	pausedAt                   time.Time
+	pausedFor                  time.Duration
+	direction                  Direction
+	countdown                  bool
+	gradient                   []RGB
+	newlineOnFinish            bool
+	mode                       Mode
+	lineStep                   int
+	lineProgress               int
+	linePrinted                bool
+	onRender                   func(Snapshot)
+	onFinish                   func(Snapshot)
+	finished                   bool
+	colorPercent               bool
+	percentInBar               bool
+	headFrames                 []string
+	completeCells              []string
+	incompleteCells            []string
+	hideFullHead               bool
+	hideZeroHead               bool
+	rounding                   Rounding
+	overshoot                  OvershootPolicy
+	etaDone                    string
+	padPercent                 bool
+	padCount                   bool
+	hideUntilStart             bool
+	begun                      bool
+	timeRenders                bool
+	renderDuration             time.Duration
+	countBytes                 bool
+	bytesDone                  int64
+	bytesTotal                 int64
+	byteRate                   float64
+	byteWindow                 rateWindow
+	smoothedBytes              rateAverage
+	roundDown                  bool
+	cancelled                  bool
+	done                       chan struct{}
+	refreshInterval            time.Duration
+	refresher                  *refresher
+	hooks                      []func()
+	redrawOnResize             bool
+	watchingResize             bool
+	notifyResize               func() chan os.Signal
+	tokenColor                 Color
+	decimalSep                 string
+	percentSpace               bool
 }
 
 // ContextValue is a tuple that defines a substitution for a custom verb
 type ContextValue struct {
 	verb  string
 	value *stringish
+	fn    func(*Bar) string
 }
 
 // Context is a wrapper type for a slice of ContextValues
@@ -38,129 +119,940 @@ type Context []*ContextValue
 
 // Ctx is a helper for creating a ContextValue tuple
 func Ctx(verb string, value interface{}) *ContextValue {
+	checkCustomVerb(verb)
+
+	return &ContextValue{
+		verb:  verb,
+		value: newStringish(value),
+	}
+}
+
+// CtxFunc is like Ctx, but the custom verb's value is provided by calling fn
+// each time the bar is drawn, for values that change as the bar progresses
+// (such as the name of the file being processed). fn is called while the
+// bar is being drawn, so it must not call any of the bar's methods.
+func CtxFunc(verb string, fn func(*Bar) string) *ContextValue {
+	checkCustomVerb(verb)
+
+	return &ContextValue{
+		verb: verb,
+		fn:   fn,
+	}
+}
+
+// checkCustomVerb panics if verb can't be used as a custom verb
+func checkCustomVerb(verb string) {
+	if err := validateCustomVerb(verb, false); err != nil {
+		panic(err.Error())
+	}
+}
+
+// validateCustomVerb returns an error if verb can't be used as a custom verb
+func validateCustomVerb(verb string, foldCase bool) error {
+	if verb == "" {
+		return fmt.Errorf("a custom verb may not be empty")
+	}
+
 	if verb[0] == ':' {
-		panic(fmt.Sprintf("don't prefix your custom verb declaration with a `:`, it's implied (at %s)", verb))
+		return fmt.Errorf("don't prefix your custom verb declaration with a `:`, it's implied (at %s)", verb)
 	}
 
-	if verb == "bar" || verb == "percent" || verb == "rate" || verb == "eta" {
-		panic(fmt.Sprintf(":%s is a reserved verb, please choose another name", verb))
+	if isReservedVerb(verb, foldCase) {
+		return fmt.Errorf(":%s is a reserved verb, please choose another name", verb)
 	}
 
-	return &ContextValue{
-		verb:  verb,
-		value: newStringish(value),
+	return nil
+}
+
+// valueFor returns the value substituted for the custom verb when b is drawn
+func (v *ContextValue) valueFor(b *Bar) string {
+	if v.fn != nil {
+		return v.fn(b)
 	}
+
+	return v.value.String()
 }
 
+// defaultRateSmoothing is the factor used to smooth the rate shown by
+// :ratesmoothed, unless another is set with WithRateSmoothing
+const defaultRateSmoothing = 0.1
+
 const defaultFormat = " :bar :percent :rate ops/s "
 
-// New creates a new instance of bar.Bar with the given total and
-// returns a reference to it
-func New(t int) *Bar {
-	return &Bar{
-		progress:     0,
-		total:        t,
-		width:        20,
-		start:        "(",
-		complete:     "█",
-		head:         "█",
-		incomplete:   " ",
-		end:          ")",
-		closed:       false,
-		startedAt:    time.Now(),
-		rate:         0,
-		formatString: defaultFormat,
-		format:       tokenize(defaultFormat, []string{}),
-		callback:     noop,
-		output:       initializeStdout(),
-	}
+var defaultSpinner = []string{"|", "/", "-", "\\"}
+
+// New creates a new instance of bar.Bar with the given total and options
+// and returns a reference to it. Without any options, the bar is 20 cells
+// wide and uses the default format. It panics if the options are invalid;
+// use TryNewWithOpts to handle the error instead.
+func New(t int, opts ...Option) *Bar {
+	return NewWithOpts(append([]Option{WithDimensions(t, 20)}, opts...)...)
 }
 
 // Tick increments the bar's progress by 1
 func (b *Bar) Tick() {
+	b.mu.Lock()
+	defer b.unlock()
+
 	if !b.canUpdate("Tick") {
 		return
 	}
 
-	b.TickAndUpdate(nil)
+	b.warnOvershot(b.update(b.progress+1, nil))
 }
 
 // TickAndUpdate is a helper function for calling Tick
 // followed by Update
 func (b *Bar) TickAndUpdate(ctx Context) {
+	b.mu.Lock()
+	defer b.unlock()
+
 	if !b.canUpdate("TickAndUpdate") {
 		return
 	}
 
-	b.Update(b.progress+1, ctx)
+	b.warnOvershot(b.update(b.progress+1, ctx))
 }
 
 // Update sets the bar's progress to an arbitrary value
 // and optionally updates the bar's context
 func (b *Bar) Update(progress int, ctx Context) {
+	b.mu.Lock()
+	defer b.unlock()
+
 	if !b.canUpdate("Update") {
 		return
 	}
 
-	duration := time.Now().Sub(b.startedAt)
-	b.rate = float64(b.progress) / duration.Seconds()
-	b.eta = time.Duration(float64(b.total-b.progress)/b.rate) * time.Second
+	b.warnOvershot(b.update(progress, ctx))
+}
 
-	b.progress = progress
+// Add increments the bar's progress by n; it is safe for concurrent use. An
+// error is only returned if this would take the bar past its total and it
+// was created with WithOvershootPolicy(OvershootError).
+func (b *Bar) Add(n int) error {
+	b.mu.Lock()
+	defer b.unlock()
 
-	if ctx != nil {
-		b.context = ctx
-		b.format = tokenize(b.formatString, ctx.customVerbs())
+	if !b.canUpdate("Add") {
+		return nil
+	}
+
+	return b.update(b.progress+n, nil)
+}
+
+// Set sets the bar's progress to n; it is safe for concurrent use. An error
+// is only returned if n is past the bar's total and it was created with
+// WithOvershootPolicy(OvershootError).
+func (b *Bar) Set(n int) error {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if !b.canUpdate("Set") {
+		return nil
+	}
+
+	return b.update(n, nil)
+}
+
+// Range calls fn for each index from 0 to n-1 in turn, advancing the bar by
+// one after each call, and finishes the bar once they've all returned. This
+// replaces a loop that calls Tick itself; n is usually the bar's total.
+func (b *Bar) Range(n int, fn func(i int)) {
+	for i := 0; i < n; i++ {
+		fn(i)
+		b.Tick()
+	}
+
+	b.Finish()
+}
+
+// SetCustomVerb sets the value displayed for the custom verb and redraws
+// the bar, without needing to pass the rest of the context to Update. If
+// the verb isn't in the bar's context yet, it's added as long as it's used
+// by the bar's format.
+func (b *Bar) SetCustomVerb(verb, value string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.canUpdate("SetCustomVerb") {
+		return
+	}
+
+	// copy the context rather than modifying it, since it may have been
+	// provided by the caller
+	ctx := append(Context{}, b.context...)
+	if i := ctx.index(verb); i >= 0 {
+		ctx[i] = Ctx(verb, value)
+	} else {
+		ctx = append(ctx, Ctx(verb, value))
+
+		format := mustParseFormat(b.formatString, ctx.customVerbs(), b.formatOpts)
+		if !format.usesCustomVerb(verb) {
+			return
+		}
+
+		b.format = format
+	}
+
+	b.context = ctx
+
+	if b.throttled(b.now()) {
+		return
 	}
 
 	b.write()
 }
 
-// Done finalizes the bar and prints it followed by a new line
+// AddVerb defines the custom verb, or replaces its value if it's already
+// defined, without needing to build the bar's context by hand; the value is
+// shown the next time the bar is drawn. Defining a verb that the bar's format
+// doesn't use isn't an error. It returns an error if verb is reserved or
+// otherwise can't be used as a custom verb.
+func (b *Bar) AddVerb(verb string, value fmt.Stringer) error {
+	return b.addVerb(verb, value)
+}
+
+// AddVerbString is like AddVerb, but for a value that doesn't change
+func (b *Bar) AddVerbString(verb, value string) error {
+	return b.addVerb(verb, value)
+}
+
+// addVerb defines the custom verb with a string or fmt.Stringer value
+func (b *Bar) addVerb(verb string, value interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := validateCustomVerb(verb, b.formatOpts.foldCase); err != nil {
+		return err
+	}
+
+	if value == nil {
+		return fmt.Errorf("custom verb :%s may not have a nil value", verb)
+	}
+
+	// copy the context rather than modifying it, since it may have been
+	// provided by the caller
+	ctx := append(Context{}, b.context...)
+	cv := &ContextValue{verb: verb, value: newStringish(value)}
+	if i := ctx.index(verb); i >= 0 {
+		ctx[i] = cv
+	} else {
+		ctx = append(ctx, cv)
+		b.format = mustParseFormat(b.formatString, ctx.customVerbs(), b.formatOpts)
+	}
+
+	b.context = ctx
+
+	return nil
+}
+
+// SetTotal changes the bar's total, such as once it has been discovered
+// partway through an operation; the new total is reflected the next time
+// the bar is drawn. Progress beyond the new total is clamped to it, which
+// completes the bar (calling its OnFinish hook) as if it had been updated.
+func (b *Bar) SetTotal(n int) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if !b.canUpdate("SetTotal") {
+		return
+	}
+
+	b.total = n
+	if n > 0 && b.progress > n {
+		b.progress = n
+	}
+
+	b.updateETA()
+
+	if !b.indeterminate() && b.progress >= b.total {
+		b.notifyFinished()
+	}
+}
+
+// Done finalizes the bar and prints it followed by a new line, unless the
+// bar was stopped by Watch
 func (b *Bar) Done() {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if b.cancelled {
+		return
+	}
+
+	b.finish()
+}
+
+// Finish completes the bar by setting its progress to its total, then
+// finalizes it and prints it followed by a new line. Unlike Done, calling
+// it on a bar that's already finished has no effect. It returns the same
+// error as Err, if the bar couldn't be written.
+func (b *Bar) Finish() error {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if !b.closed {
+		if !b.indeterminate() {
+			b.progress = b.total
+			b.updateETA()
+		}
+
+		if b.bytesDone < b.bytesTotal {
+			b.bytesDone = b.bytesTotal
+		}
+
+		b.finish()
+	}
+
+	return b.err()
+}
+
+// Err returns the first error writing the bar to its output (such as a
+// closed pipe or a full disk), so that callers can stop once the output has
+// gone away. Nothing more is written to the output after an error. Errors
+// are only reported for writers provided to WithWriter, and outputs that
+// implement `Err() error`.
+func (b *Bar) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.err()
+}
+
+// err returns the first error writing to the bar's output, if it reports
+// one; the caller must hold b.mu
+func (b *Bar) err() error {
+	if out, ok := b.output.(interface {
+		Err() error
+	}); ok {
+		return out.Err()
+	}
+
+	return nil
+}
+
+// Reset returns the bar to its initial state so that it can be reused for
+// another operation, keeping its format and styling. Its progress, timing
+// and animations start over, and a finished bar may be updated again.
+func (b *Bar) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.progress = 0
+	b.closed = false
+	b.cancelled = false
+	b.startedAt = b.now()
+	b.started = time.Time{}
+	b.rate = 0
+	b.eta = 0
+	b.frame = 0
+	b.lastDraw = time.Time{}
+	b.smoothed = rateAverage{}
+	b.window = rateWindow{}
+	b.paused = false
+	b.pausedFor = 0
+	b.linePrinted = false
+	b.finished = false
+	b.begun = false
+	b.bytesDone = 0
+	b.byteRate = 0
+	b.byteWindow = rateWindow{}
+	b.smoothedBytes = rateAverage{}
+}
+
+// Pause stops the clock used to measure the bar's elapsed time, rate and
+// ETA, such as while waiting on user input, so that the time spent paused
+// doesn't count against its throughput. The bar can still be updated and
+// drawn while paused, but its timing is frozen until Resume is called.
+func (b *Bar) Pause() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.paused {
+		return
+	}
+
+	b.paused = true
+	b.pausedAt = b.now()
+}
+
+// Resume restarts the clock stopped by Pause
+func (b *Bar) Resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.paused {
+		return
+	}
+
+	b.paused = false
+	b.pausedFor += b.now().Sub(b.pausedAt)
+}
+
+// close marks the bar as closed, stopping any goroutines that stop along
+// with it; the caller must hold b.mu
+func (b *Bar) close() {
 	b.closed = true
+	b.watchingResize = false
+
+	if b.done != nil {
+		close(b.done)
+		b.done = nil
+	}
+}
+
+// finish closes the bar and draws it one last time, regardless of any
+// throttling, and queues its callback; the caller must hold b.mu and release
+// it with unlock
+func (b *Bar) finish() {
+	b.close()
 	b.write()
-	fmt.Println()
-	b.callback()
+	b.notifyFinished()
+
+	if b.group == nil && b.newlineOnFinish && b.mode == ModeBar && !b.lineMode() && !b.blank() && !b.hidden() {
+		b.printLine("")
+	}
+
+	b.hooks = append(b.hooks, b.callback)
 }
 
 // Interrupt prints s above the bar
 func (b *Bar) Interrupt(s string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	if b.closed {
 		return
 	}
 
+	// a bar in ModeJSON writes its own lines, even in a group
+	if b.mode == ModeJSON {
+		b.writeJSONMessage(s)
+		return
+	}
+
+	if b.group != nil {
+		b.group.interrupt(s)
+		return
+	}
+
+	if b.lineMode() {
+		b.printLine(s)
+		return
+	}
+
 	b.output.ClearLine()
-	fmt.Println(s)
+	b.printLine(s)
 	b.write()
 }
 
+// Clear erases the bar from the terminal and returns the cursor to the
+// first column without drawing it again, such as before printing an error.
+// It has no effect if the bar's output isn't a terminal.
+func (b *Bar) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.tty {
+		return
+	}
+
+	b.output.ClearLine()
+}
+
 // Interruptf passes the given input to fmt.Sprintf and prints
 // it above the bar
 func (b *Bar) Interruptf(format string, s ...interface{}) {
 	b.Interrupt(fmt.Sprintf(format, s...))
 }
 
+// update sets the bar's progress and context and redraws it, unless the
+// progress is rejected by the bar's overshoot policy; the caller must hold
+// b.mu and release it with unlock
+func (b *Bar) update(progress int, ctx Context) error {
+	progress, err := b.overshot(progress)
+	if err != nil {
+		return err
+	}
+
+	now := b.clock()
+	if b.started.IsZero() {
+		b.started = now
+	}
+
+	if b.rateWindow > 0 {
+		b.sampleWindow(now, progress)
+	} else if b.rateSmoothing > 0 {
+		b.progress = progress
+	} else {
+		duration := now.Sub(b.startedAt)
+		b.rate = float64(b.progress) / duration.Seconds()
+		b.updateETA()
+
+		b.progress = progress
+	}
+
+	if b.progress > 0 {
+		b.begun = true
+	}
+
+	// the smoothed rate is kept up to date even when it isn't the bar's
+	// rate, for :ratesmoothed
+	b.sampleRate(now)
+
+	if ctx != nil {
+		// the format only needs to be tokenized again if the set of
+		// custom verbs has changed, not just their values
+		if !ctx.sameVerbs(b.context) {
+			b.format = mustParseFormat(b.formatString, ctx.customVerbs(), b.formatOpts)
+		}
+
+		b.context = ctx
+	}
+
+	if !b.throttled(b.now()) {
+		b.write()
+	}
+
+	if !b.indeterminate() && b.progress >= b.total {
+		b.notifyFinished()
+	}
+
+	return nil
+}
+
+// warnOvershot reports an error from update for methods that can't return
+// it
+func (b *Bar) warnOvershot(err error) {
+	if err != nil {
+		b.warnf("%v", err)
+	}
+}
+
+// notifyFinished queues a call to the bar's OnFinish hook, unless it has
+// already been called since the bar was created or reset; the caller must
+// hold b.mu and release it with unlock
+func (b *Bar) notifyFinished() {
+	if b.finished {
+		return
+	}
+
+	b.finished = true
+	if fn := b.onFinish; fn != nil {
+		s := b.snapshot()
+		b.hooks = append(b.hooks, func() { fn(s) })
+	}
+}
+
+// unlock releases b.mu, then calls any hooks that were queued while it was
+// held, so that they're free to call the bar's methods themselves
+func (b *Bar) unlock() {
+	hooks := b.hooks
+	b.hooks = nil
+	b.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// sampleRate folds the progress made since the last sample into the
+// smoothed rate and, if it's the bar's rate, recomputes the ETA from it
+func (b *Bar) sampleRate(now time.Time) {
+	factor := b.rateSmoothing
+	if factor == 0 {
+		factor = defaultRateSmoothing
+	}
+
+	if b.smoothed.sample(b.startedAt, now, int64(b.progress), factor) && b.rateSmoothing > 0 {
+		b.rate = b.smoothed.rate()
+		b.updateETA()
+	}
+}
+
+// smoothedRate returns the rate averaged by sampleRate
+func (b *Bar) smoothedRate() float64 {
+	return b.smoothed.rate()
+}
+
+// throttled reports whether a redraw at now should be skipped because the
+// bar was drawn less than minInterval ago. The bar is always drawn once it
+// is complete so that its final state is shown (which an indeterminate bar
+// never is until it's finished).
+func (b *Bar) throttled(now time.Time) bool {
+	if b.minInterval <= 0 || b.lastDraw.IsZero() || (!b.indeterminate() && b.progress >= b.total) {
+		return false
+	}
+
+	return now.Sub(b.lastDraw) < b.minInterval
+}
+
+// write redraws the bar; the caller must hold b.mu
 func (b *Bar) write() {
+	if b.hidden() {
+		return
+	}
+
+	b.lastDraw = b.now()
+
+	if b.mode == ModeJSON {
+		b.writeJSON()
+		return
+	}
+
+	// a format with nothing to show (such as "" or " ") isn't drawn at all,
+	// rather than clearing the line only to print a blank one
+	if b.blank() {
+		return
+	}
+
+	// the terminal's size is only watched once there's a bar on it to redraw
+	if b.redrawOnResize && !b.watchingResize && !b.closed && b.mode == ModeBar && !b.lineMode() {
+		b.watchResize(b.notifyResize())
+	}
+
+	if b.group != nil {
+		b.group.update(b, b.render())
+		return
+	}
+
+	if b.lineMode() {
+		b.writeLine()
+		return
+	}
+
 	b.output.ClearLine()
-	b.output.Printf("%s", b)
+	b.output.Printf("%s", b.render())
+}
+
+// hidden reports whether the bar isn't drawn yet because it was created with
+// WithHideUntilStart and hasn't made any progress
+func (b *Bar) hidden() bool {
+	return b.hideUntilStart && !b.begun && b.progress <= 0
+}
+
+// blank reports whether the bar renders nothing visible: its format is blank
+// and there's no prefix or suffix around it
+func (b *Bar) blank() bool {
+	return b.prefix == "" && b.suffix == "" && tokens(b.format).blank()
+}
+
+// lineMode reports whether the bar is written line by line, because its
+// output isn't a terminal that it could be redrawn on
+func (b *Bar) lineMode() bool {
+	return b.mode == ModeBar && !b.tty
+}
+
+// writeLine prints the bar on a new line if its progress has reached
+// another tenth of its total since the last line was printed (or, for
+// indeterminate bars, on every draw). When the bar is finished, its final
+// state is printed unless it was already. The caller must hold b.mu.
+func (b *Bar) writeLine() {
+	step := -1
+	if !b.indeterminate() {
+		step = int(b.prog() * 10)
+	}
+
+	if b.linePrinted {
+		if b.closed && b.progress == b.lineProgress {
+			return
+		}
+
+		if !b.closed && step >= 0 && step == b.lineStep {
+			return
+		}
+	}
+
+	b.lineStep, b.lineProgress, b.linePrinted = step, b.progress, true
+	b.printLine(b.render())
+}
+
+// printLine writes s to the bar's output followed by a new line, without
+// any escape sequences to clear the current line
+func (b *Bar) printLine(s string) {
+	switch out := b.output.(type) {
+	case io.Writer:
+		fmt.Fprintln(out, s)
+	case *stdout:
+		fmt.Fprintln(os.Stdout, s)
+	default:
+		out.Printf("%s\n", s)
+	}
 }
 
 func (b *Bar) canUpdate(method string) bool {
+	// once a bar is cancelled, anything still updating it is expected to
+	// be winding down too, so there's nothing to warn about
+	if b.closed && b.cancelled {
+		return false
+	}
+
 	if b.closed {
-		fmt.Fprintf(os.Stderr, "bar: attempted to call %s on a closed bar, this is likely caused by a memory leak", method)
+		b.warnf("bar: attempted to call %s on a closed bar, this is likely caused by a memory leak", method)
 		return false
 	}
 
 	return true
 }
 
+// warnf reports a diagnostic message to stderr, rather than to the bar's
+// output, which may be meant to hold nothing but the bar
+func (b *Bar) warnf(format string, vals ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, vals...)
+}
+
+// painter returns a painter writing to sb that respects whether the bar's
+// output supports colors, and that paints everything in the color option of
+// the token being printed, if it has one
+func (b *Bar) painter(sb *strings.Builder) *painter {
+	return &painter{sb: sb, enabled: b.colorize, color: b.tokenColor}
+}
+
+// clock returns the current time as measured by the bar's timing, which
+// excludes any time spent paused and stands still while the bar is paused
+func (b *Bar) clock() time.Time {
+	if b.paused {
+		return b.pausedAt.Add(-b.pausedFor)
+	}
+
+	return b.now().Add(-b.pausedFor)
+}
+
+// fillColor returns the color of the completed portion of the bar, which
+// is that of the highest threshold reached (if any) or completeColor
+func (b *Bar) fillColor() Color {
+	c := b.completeColor
+	if b.indeterminate() {
+		return c
+	}
+
+	percent := float64(b.progress) * 100 / float64(b.total)
+	for _, threshold := range b.thresholds {
+		if percent >= threshold.Percent {
+			c = threshold.Color
+		}
+	}
+
+	return c
+}
+
+// ETA returns the estimated time remaining before the bar completes, as
+// well as a bool determining whether an estimate is available yet (it isn't
+// until the bar has established a rate of progress). Once SetBytesTotal has
+// been called, the estimate is based on bytes rather than progress.
+func (b *Bar) ETA() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.estimate()
+}
+
+// estimate is like ETA; the caller must hold b.mu
+func (b *Bar) estimate() (time.Duration, bool) {
+	if b.bytesTotal > 0 {
+		return b.estimateBytes()
+	}
+
+	if b.indeterminate() || !b.hasRate() {
+		return 0, false
+	}
+
+	return b.eta, true
+}
+
+// hasRate reports whether the bar has established a rate of progress from
+// which to estimate how long is left. There isn't one before any progress
+// has been made, or while no time has passed since the bar started.
+func (b *Bar) hasRate() bool {
+	return b.rate > 0 && !math.IsInf(b.rate, 0)
+}
+
+// updateETA recomputes the time remaining from the bar's rate, leaving it
+// at zero if there is no rate to divide by
+func (b *Bar) updateETA() {
+	if !b.hasRate() {
+		b.eta = 0
+		return
+	}
+
+	b.eta = time.Duration(float64(b.total-b.progress)/b.rate) * time.Second
+}
+
+// indeterminate reports whether the bar's total is unknown, in which case
+// it animates rather than displaying its progress
+func (b *Bar) indeterminate() bool {
+	return b.total <= 0
+}
+
+// estimateDone reports whether the work the bar's estimate is based on is
+// done: its bytes once SetBytesTotal has been called, and its progress
+// otherwise
+func (b *Bar) estimateDone() bool {
+	if b.bytesTotal > 0 {
+		return b.bytesDone >= b.bytesTotal
+	}
+
+	return !b.indeterminate() && b.progress >= b.total
+}
+
+// Percent returns the fraction of the bar's total that has been completed,
+// from 0 to 1; progress beyond the total (or below zero) is clamped, and
+// the fraction is always 0 for indeterminate bars
+func (b *Bar) Percent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.prog()
+}
+
+// prog is like Percent; the caller must hold b.mu. Clamping the fraction
+// ensures the bar is never drawn wider than its width when progress
+// overshoots its total (or narrower when progress is negative).
 func (b *Bar) prog() float64 {
-	return float64(b.progress) / float64(b.total)
+	if b.indeterminate() {
+		return 0
+	}
+
+	return math.Max(0, math.Min(1, float64(b.progress)/float64(b.total)))
+}
+
+// filled returns the fraction of the bar's width that should be filled,
+// which shrinks as progress is made in countdown mode
+func (b *Bar) filled() float64 {
+	if b.countdown {
+		return 1 - b.prog()
+	}
+
+	return b.prog()
+}
+
+// cells returns how many of the width cells of the bar should be filled
+func (b *Bar) cells(width int) int {
+	return int(b.rounding.apply(b.filled() * float64(width)))
+}
+
+// percent returns the bar's percentage formatted to the given precision
+func (b *Bar) percent(precision int) string {
+	return b.formatPercent(b.prog()*100, precision)
+}
+
+// flooredPercent is like percent, but always rounds down, so that it never
+// shows more progress than has been made
+func (b *Bar) flooredPercent(precision int) string {
+	scale := math.Pow(10, float64(precision))
+	return b.formatPercent(RoundingFloor.apply(b.prog()*100*scale)/scale, precision)
+}
+
+// formatPercent formats the percentage v to the given precision, rounding
+// it the same way as the bar's own percentage
+func (b *Bar) formatPercent(v float64, precision int) string {
+	if b.roundDown {
+		scale := math.Pow(10, float64(precision))
+		v = b.rounding.apply(v*scale) / scale
+	}
+
+	s := strconv.FormatFloat(v, 'f', precision, 64)
+	if b.decimalSep != "" {
+		s = strings.Replace(s, ".", b.decimalSep, 1)
+	}
+
+	if b.percentSpace {
+		return s + " %"
+	}
+
+	return s + "%"
+}
+
+// completeCell returns a completed cell of the bar, pos cells from where it
+// starts filling
+func (b *Bar) completeCell(pos int) barCell {
+	return patternCell(b.completeCells, pos)
+}
+
+// incompleteCell returns an incomplete cell of the bar, pos cells from its
+// left edge
+func (b *Bar) incompleteCell(pos int) barCell {
+	return patternCell(b.incompleteCells, pos)
+}
+
+// patternCell returns the cell pos cells into the tiling of cells
+func patternCell(cells []string, pos int) barCell {
+	return barCell{
+		glyph: cells[pos%len(cells)],
+		wide:  cells[(pos+1)%len(cells)] == wideTail,
+	}
+}
+
+// headGlyph returns the bar's head for the current frame
+func (b *Bar) headGlyph() string {
+	if len(b.headFrames) == 0 {
+		return b.head
+	}
+
+	return b.headFrames[b.frame%len(b.headFrames)]
+}
+
+// remaining returns the number of items left before the bar is complete,
+// which is never negative even if progress has overshot the total
+func (b *Bar) remaining() int {
+	if b.progress >= b.total {
+		return 0
+	}
+
+	return b.total - b.progress
+}
+
+// elapsed returns the wall-clock time since the bar's first progress
+// update, truncated to the second
+func (b *Bar) elapsed() time.Duration {
+	if b.started.IsZero() {
+		return 0
+	}
+
+	return b.clock().Sub(b.started).Truncate(time.Second)
+}
+
+// sameVerbs reports whether c defines the same custom verbs, in the same
+// order, as other
+func (c Context) sameVerbs(other Context) bool {
+	if len(c) != len(other) {
+		return false
+	}
+
+	for i := range c {
+		if c[i].verb != other[i].verb {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookup returns the definition of verb in c, as well as a bool determining
+// whether one was found
+func (c Context) lookup(verb string) (*ContextValue, bool) {
+	if i := c.index(verb); i >= 0 {
+		return c[i], true
+	}
+
+	return nil, false
+}
+
+// index returns the index of the definition of verb in c, or -1 if there
+// isn't one
+func (c Context) index(verb string) int {
+	for i, def := range c {
+		if def.verb == verb {
+			return i
+		}
+	}
+
+	return -1
 }
 
 func (c Context) customVerbs() []string {
-	verbs := make([]string, len(c))
+	verbs := make([]string, 0, len(c))
 
 	for _, def := range c {
 		verbs = append(verbs, def.verb)
@@ -169,16 +1061,186 @@ func (c Context) customVerbs() []string {
 	return verbs
 }
 
+// Render returns the bar's formatted output without printing it, for
+// embedding the bar into your own layout
+func (b *Bar) Render() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.render()
+}
+
 func (b *Bar) String() string {
-	var buf bytes.Buffer
+	return b.Render()
+}
+
+// DebugString returns the bar's output as WithDebug would draw it, with each
+// token of its format replaced by a description of its type and internal
+// state, for inspecting how a format was parsed. Unlike Render, it doesn't
+// advance the bar's animations or call its OnRender hook.
+func (b *Bar) DebugString() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var sb strings.Builder
+	for _, t := range b.format {
+		sb.WriteString(t.debug(b))
+	}
+
+	return sb.String()
+}
 
-	for _, s := range b.format {
-		if b.debug {
-			buf.WriteString(s.debug(b))
-		} else {
-			buf.WriteString(s.print(b))
+// LastRenderDuration returns how long the bar's most recent render took,
+// including any custom verb values and OnRender hook, which helps to choose a
+// minimum interval between draws. It's always 0 unless the bar was created
+// with WithRenderTiming.
+func (b *Bar) LastRenderDuration() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.renderDuration
+}
+
+// Tokens returns a description of each of the tokens the bar's format was
+// parsed into, in order, as printed by DebugString. This is useful to check
+// how a format string was parsed without rendering the bar.
+func (b *Bar) Tokens() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	descriptions := make([]string, len(b.format))
+	for i, t := range b.format {
+		descriptions[i] = t.debug(b)
+	}
+
+	return descriptions
+}
+
+// render formats the bar according to its tokens; the caller must hold b.mu
+func (b *Bar) render() string {
+	if b.timeRenders {
+		// real time is used even if the bar has its own clock, since this
+		// measures the cost of rendering rather than the bar's progress
+		defer func(start time.Time) { b.renderDuration = time.Since(start) }(time.Now())
+	}
+
+	// reuse the buffer from the previous render to avoid growing a new one
+	buf := append(b.buf[:0], b.prefix...)
+
+	if b.widthPercent > 0 && !b.fitWidth {
+		if cols, ok := b.termWidth(); ok {
+			b.fittedWidth = cols * b.widthPercent / 100
+			if b.fittedWidth < 1 {
+				b.fittedWidth = 1
+			}
+
+			defer func() { b.fittedWidth = 0 }()
 		}
 	}
 
-	return buf.String()
+	if (b.fitWidth || tokens(b.format).fills()) && !b.debug {
+		for _, s := range b.fitTokens() {
+			buf = append(buf, s...)
+		}
+	} else {
+		for _, s := range b.format {
+			if b.debug {
+				buf = append(buf, s.debug(b)...)
+			} else {
+				buf = append(buf, s.print(b)...)
+			}
+		}
+	}
+
+	buf = append(buf, b.suffix...)
+	b.buf = buf
+
+	// advance any animations (such as the indeterminate bar) for the next render
+	b.frame++
+
+	if b.onRender != nil {
+		b.onRender(b.snapshot())
+	}
+
+	if b.maxWidth > 0 {
+		return truncate(string(buf), b.maxWidth, b.ellipsis)
+	}
+
+	return string(buf)
+}
+
+// fitTokens prints each of the bar's tokens so that the whole line fills the
+// terminal, sizing any bar tokens without an explicit width (when fitting the
+// bar with WithFitWidth) and then any :fill tokens. The other tokens are
+// printed first so that their width can be measured, and the remaining
+// columns are split between the bar tokens, with whatever they leave over
+// split between the fills. If the terminal's width can't be found, the
+// configured width is used instead, and fills are left empty.
+func (b *Bar) fitTokens() []string {
+	parts := make([]string, len(b.format))
+	// the prefix and suffix are on the line too, even though they aren't tokens
+	used, flexible, fills := displayWidth(b.prefix)+displayWidth(b.suffix), 0, 0
+
+	for i, t := range b.format {
+		if b.fitWidth && isFlexibleBar(t) {
+			used += displayWidth(b.start) + displayWidth(b.end)
+			flexible++
+			continue
+		}
+
+		if isFill(t) {
+			fills++
+			continue
+		}
+
+		parts[i] = t.print(b)
+		used += displayWidth(parts[i])
+	}
+
+	cols, ok := b.termWidth()
+	if ok && flexible > 0 {
+		b.fittedWidth = (cols - used) / flexible
+		if b.fittedWidth < 1 {
+			b.fittedWidth = 1
+		}
+
+		defer func() { b.fittedWidth = 0 }()
+	}
+
+	for i, t := range b.format {
+		if b.fitWidth && isFlexibleBar(t) {
+			parts[i] = t.print(b)
+			used += displayWidth(parts[i]) - displayWidth(b.start) - displayWidth(b.end)
+		}
+	}
+
+	if !ok || fills == 0 || used >= cols {
+		return parts
+	}
+
+	// the fills on the left get any columns that can't be split evenly
+	remaining := cols - used
+	for i, t := range b.format {
+		if isFill(t) {
+			n := (remaining + fills - 1) / fills
+			parts[i] = strings.Repeat(" ", n)
+			remaining -= n
+			fills--
+		}
+	}
+
+	return parts
+}
+
+// isFill reports whether t is a :fill token
+func isFill(t token) bool {
+	_, ok := unwrapToken(t).(fillToken)
+	return ok
+}
+
+// isFlexibleBar reports whether t is a bar token that can be resized to
+// fit the terminal (one that wasn't given an explicit width)
+func isFlexibleBar(t token) bool {
+	bt, ok := unwrapToken(t).(barToken)
+	return ok && bt.width == 0
 }
@@ -0,0 +1,1683 @@
+package bar
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// discardOutput is an Output that drops everything written to it
+type discardOutput struct{}
+
+func (discardOutput) ClearLine()                                {}
+func (discardOutput) Printf(format string, vals ...interface{}) {}
+
+// fakeClock is a manually advanced replacement for time.Now
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// newTestBar returns a bar that discards its output and reads time
+// from the given clock
+func newTestBar(clock *fakeClock, opts ...Option) *Bar {
+	opts = append([]Option{WithDimensions(10, 10), WithOutput(discardOutput{})}, opts...)
+	b := NewWithOpts(opts...)
+	b.now = clock.now
+	b.startedAt = clock.now()
+	return b
+}
+
+func TestElapsed(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock)
+	tkn := elapsedToken{}
+
+	if got := tkn.print(b); got != "0s" {
+		t.Errorf("elapsed before first update\n\n  got %#v\n  want %#v", got, "0s")
+	}
+
+	clock.advance(5 * time.Second)
+	b.Tick()
+
+	if got := tkn.print(b); got != "0s" {
+		t.Errorf("elapsed at first update\n\n  got %#v\n  want %#v", got, "0s")
+	}
+
+	var testCases = []struct {
+		advance  time.Duration
+		expected string
+	}{
+		{1500 * time.Millisecond, "1s"},
+		{500 * time.Millisecond, "2s"},
+		{time.Minute, "1m2s"},
+		{time.Hour, "1h1m2s"},
+	}
+
+	for i, testCase := range testCases {
+		clock.advance(testCase.advance)
+		b.Tick()
+
+		if got := tkn.print(b); got != testCase.expected {
+			t.Errorf("[%d] elapsedToken.print\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	var testCases = []struct {
+		progress, total int
+		expected        string
+	}{
+		{0, 0, "0/0"},
+		{0, 100, "0/100"},
+		{37, 100, "37/100"},
+		{100, 100, "100/100"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(testCase.total, 10))
+		b.progress = testCase.progress
+
+		if got := (countToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] countToken.print\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+}
+
+func TestProgressAndTotal(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(120, 10), WithFormat("done: :progress, total: :total"))
+	b.Set(37)
+
+	if got, want := b.Render(), "done: 37, total: 120"; got != want {
+		t.Errorf("render of :progress and :total\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	var testCases = []struct {
+		progress, total int
+		expected        string
+	}{
+		{0, 100, "100"},
+		{37, 100, "63"},
+		{100, 100, "0"},
+		{110, 100, "0"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(testCase.total, 10))
+		b.progress = testCase.progress
+
+		if got := (remainingToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] remainingToken.print\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+}
+
+func TestBarTokenWidth(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(10, 10), WithDisplay("[", "=", ">", " ", "]"))
+	b.progress = 5
+
+	var testCases = []struct {
+		tkn      barToken
+		expected string
+	}{
+		{barToken{}, "[====>     ]"},
+		{barToken{width: 4}, "[=>  ]"},
+		{barToken{width: 20}, "[=========>          ]"},
+	}
+
+	for i, testCase := range testCases {
+		if got := testCase.tkn.print(b); got != testCase.expected {
+			t.Errorf("[%d] %#v.print\n\n  got %#v\n  want %#v", i, testCase.tkn, got, testCase.expected)
+		}
+	}
+}
+
+func TestPercentPrecision(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(100000, 10))
+	b.progress = 42195
+
+	var testCases = []struct {
+		format   string
+		expected string
+	}{
+		{":percent", "42.2%"},
+		{":percent(0)", "42%"},
+		{":percent(2)", "42.20%"},
+		{":percent(3)", "42.195%"},
+	}
+
+	for i, testCase := range testCases {
+		b.format = tokenize(testCase.format, nil)
+
+		if got := b.String(); got != testCase.expected {
+			t.Errorf("[%d] %#v\n\n  got %#v\n  want %#v", i, testCase.format, got, testCase.expected)
+		}
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(1000, 10))
+	b.progress = 50
+
+	var testCases = []struct {
+		format   string
+		expected string
+	}{
+		{":percentof(200)", "25.0%"},
+		{":percentof(50)", "100.0%"},
+		{":percentof(25)", "200.0%"},
+		{":percentof(3, 2)", "1666.67%"},
+		{":percentof(400,0)", "12%"},
+		{":percentof(0)", "--%"},
+		{":percentof", "--%"},
+		{":percent of total, :percentof(100) of quota", "5.0% of total, 50.0% of quota"},
+	}
+
+	for i, testCase := range testCases {
+		b.format = tokenize(testCase.format, nil)
+
+		if got := b.String(); got != testCase.expected {
+			t.Errorf("[%d] %#v\n\n  got %#v\n  want %#v", i, testCase.format, got, testCase.expected)
+		}
+	}
+}
+
+func TestPercentFloor(t *testing.T) {
+	var testCases = []struct {
+		total, progress int
+		expected        string
+	}{
+		{1000, 999, "99% 99.9% 99.9%"},
+		{1000, 496, "49% 49.6% 49.6%"},
+		{10000, 9999, "99% 99.9% 100.0%"},
+		{3, 2, "66% 66.6% 66.7%"},
+		{100, 29, "29% 29.0% 29.0%"},
+		{1000, 1000, "100% 100.0% 100.0%"},
+		{0, 0, "--% --% --%"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(testCase.total, 10), WithFormat(":percent(floor) :percent(1,floor) :percent"))
+		b.progress = testCase.progress
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%d] floored percent at %d/%d\n\n  got %#v\n  want %#v", i, testCase.progress, testCase.total, got, testCase.expected)
+		}
+	}
+}
+
+func TestPaddedFields(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(250, 10), WithFormat(":percent|:percent(0)|:count"), WithPaddedPercent(), WithPaddedCount())
+
+	var testCases = []struct {
+		progress int
+		expected string
+	}{
+		{0, "  0.0%|  0%|  0/250"},
+		{5, "  2.0%|  2%|  5/250"},
+		{50, " 20.0%| 20%| 50/250"},
+		{248, " 99.2%| 99%|248/250"},
+		{250, "100.0%|100%|250/250"},
+	}
+
+	for i, testCase := range testCases {
+		b.progress = testCase.progress
+
+		got := b.Render()
+		if got != testCase.expected {
+			t.Errorf("[%d] padded fields at %d/250\n\n  got %#v\n  want %#v", i, testCase.progress, got, testCase.expected)
+		}
+
+		if len(got) != len(testCases[len(testCases)-1].expected) {
+			t.Errorf("[%d] padded fields at %d/250 are %d columns wide", i, testCase.progress, len(got))
+		}
+	}
+
+	// padding follows the percentage's format, and applies while indeterminate
+	b = newTestBar(newFakeClock(), WithDimensions(10, 10), WithFormat(":percent"), WithPaddedPercent(), WithDecimalSeparator(","), WithSpaceBeforePercent())
+	b.progress = 1
+
+	if got, want := b.Render(), " 10,0 %"; got != want {
+		t.Errorf("padded percent with a localized format\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	b.SetTotal(0)
+	if got, want := b.Render(), "    --%"; got != want {
+		t.Errorf("padded percent of an indeterminate bar\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestPercentLocalization(t *testing.T) {
+	var testCases = []struct {
+		opts     []Option
+		format   string
+		expected string
+	}{
+		{nil, ":percent", "42.5%"},
+		{[]Option{WithDecimalSeparator(",")}, ":percent", "42,5%"},
+		{[]Option{WithDecimalSeparator(",")}, ":percent(3)", "42,500%"},
+		{[]Option{WithDecimalSeparator(",")}, ":percent(0)", "42%"},
+		{[]Option{WithSpaceBeforePercent()}, ":percent", "42.5 %"},
+		{[]Option{WithDecimalSeparator(","), WithSpaceBeforePercent()}, ":percent", "42,5 %"},
+		{[]Option{WithDecimalSeparator(","), WithSpaceBeforePercent()}, ":percentof(1000)", "8,5 %"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), append([]Option{WithDimensions(200, 10), WithFormat(testCase.format)}, testCase.opts...)...)
+		b.progress = 85
+
+		if got := b.String(); got != testCase.expected {
+			t.Errorf("[%d] %#v\n\n  got %#v\n  want %#v", i, testCase.format, got, testCase.expected)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	clock := newFakeClock()
+	info := newTestBar(clock, WithDimensions(100, 10), WithFormat(":info"))
+	manual := newTestBar(clock, WithDimensions(100, 10), WithFormat(":percent :count :rate :eta"))
+
+	for i := 0; i < 3; i++ {
+		clock.advance(time.Second)
+		info.Add(7)
+		manual.Add(7)
+
+		if got, want := info.Render(), manual.Render(); got != want {
+			t.Errorf("[%d] :info\n\n  got %#v\n  want %#v", i, got, want)
+		}
+	}
+
+	if got, want := info.Render(), "21.0% 21/100 4.7 18s"; got != want {
+		t.Errorf(":info after three updates\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithFormat("[:label(6)]"))
+
+	if got, want := b.String(), "[      ]"; got != want {
+		t.Errorf(":label(6) without a label\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	var testCases = []struct {
+		label    string
+		expected string
+	}{
+		{"ab", "[ab    ]"},
+		{"abcdef", "[abcdef]"},
+		{"abcdefgh", "[abcde…]"},
+		{"日本", "[日本  ]"},
+		{"日本語です", "[日本… ]"},
+	}
+
+	for i, testCase := range testCases {
+		b.SetCustomVerb("label", testCase.label)
+
+		got := b.String()
+		if got != testCase.expected {
+			t.Errorf("[%d] :label(6) with %#v\n\n  got %#v\n  want %#v", i, testCase.label, got, testCase.expected)
+		}
+
+		if width := displayWidth(got); width != 8 {
+			t.Errorf("[%d] width of :label(6) with %#v\n\n  got %d\n  want %d", i, testCase.label, width, 8)
+		}
+	}
+
+	b = newTestBar(newFakeClock(), WithFormat(":label :count"), WithContext(Context{Ctx("label", "a long label")}))
+	if got, want := b.String(), "a long label 0/10"; got != want {
+		t.Errorf(":label without a width\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestRatePrecisionAndSuffix(t *testing.T) {
+	b := newTestBar(newFakeClock())
+	b.rate = 12.3456
+
+	var testCases = []struct {
+		format   string
+		expected string
+	}{
+		{":rate", "12.3"},
+		{":rate(0)", "12"},
+		{":rate(2)", "12.35"},
+		{":rate(2,ops)", "12.35ops"},
+		{":rate( 1 , ops/s )", "12.3ops/s"},
+	}
+
+	for i, testCase := range testCases {
+		b.format = tokenize(testCase.format, nil)
+
+		if got := b.String(); got != testCase.expected {
+			t.Errorf("[%d] %#v\n\n  got %#v\n  want %#v", i, testCase.format, got, testCase.expected)
+		}
+	}
+}
+
+func TestIndeterminate(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(0, 4), WithDisplay("[", "=", ">", " ", "]"), WithFormat(":bar :percent"))
+	b.progress = 12
+
+	expected := []string{
+		"[=   ] --%",
+		"[ =  ] --%",
+		"[  = ] --%",
+		"[   =] --%",
+		"[  = ] --%",
+		"[ =  ] --%",
+		"[=   ] --%",
+		"[ =  ] --%",
+	}
+
+	var last string
+	for i, want := range expected {
+		got := b.String()
+		if got != want {
+			t.Errorf("[%d] indeterminate render\n\n  got %#v\n  want %#v", i, got, want)
+		}
+
+		if got == last {
+			t.Errorf("[%d] indeterminate render did not change between consecutive renders: %#v", i, got)
+		}
+
+		last = got
+	}
+}
+
+func TestIndeterminateSingleCell(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(-1, 1), WithDisplay("[", "=", ">", " ", "]"), WithFormat(":bar"))
+
+	for i := 0; i < 3; i++ {
+		if got := b.String(); got != "[=]" {
+			t.Errorf("[%d] indeterminate render\n\n  got %#v\n  want %#v", i, got, "[=]")
+		}
+	}
+}
+
+func TestSpinner(t *testing.T) {
+	var testCases = []struct {
+		frames   []string
+		expected []string
+	}{
+		{nil, []string{"|", "/", "-", "\\", "|", "/"}},
+		{[]string{"⠋", "⠙", "⠹"}, []string{"⠋", "⠙", "⠹", "⠋"}},
+		{[]string{"🌑", "🌓", "🌕"}, []string{"🌑", "🌓", "🌕", "🌑"}},
+		{[]string{"*"}, []string{"*", "*"}},
+	}
+
+	for i, testCase := range testCases {
+		opts := []Option{WithFormat(":spinner")}
+		if testCase.frames != nil {
+			opts = append(opts, WithSpinner(testCase.frames...))
+		}
+
+		b := newTestBar(newFakeClock(), opts...)
+		for j, want := range testCase.expected {
+			if got := b.String(); got != want {
+				t.Errorf("[%d] render %d of :spinner\n\n  got %#v\n  want %#v", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestSpinnerRequiresFrames(t *testing.T) {
+	if _, err := TryNewWithOpts(WithDimensions(10, 10), WithSpinner()); err == nil {
+		t.Error("TryNewWithOpts(WithSpinner()) returned no error")
+	}
+}
+
+func TestTabsArePreserved(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", ">", " ", "]"), WithFormat("done\t:bar\t:percent"))
+	b.progress = 5
+
+	if got, want := b.String(), "done\t[=>  ]\t50.0%"; got != want {
+		t.Errorf("render with tabs\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestConcurrentAdd(t *testing.T) {
+	const goroutines, increments = 50, 100
+
+	b := NewWithOpts(WithDimensions(goroutines*increments, 20), WithOutput(discardOutput{}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				b.Add(1)
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < increments; j++ {
+			_ = b.String()
+		}
+	}()
+
+	wg.Wait()
+
+	if b.progress != goroutines*increments {
+		t.Errorf("progress after concurrent Add\n\n  got %d\n  want %d", b.progress, goroutines*increments)
+	}
+
+	b.Set(10)
+	if b.progress != 10 {
+		t.Errorf("progress after Set\n\n  got %d\n  want %d", b.progress, 10)
+	}
+}
+
+func TestRender(t *testing.T) {
+	b := newTestBar(
+		newFakeClock(),
+		WithDimensions(4, 4),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithFormat(" :bar :percent (:count) "),
+	)
+	b.Add(2)
+
+	expected := fmt.Sprintf(
+		"%s%s%s%s%s",
+		spaceToken{}.print(b),
+		barToken{}.print(b),
+		spaceToken{}.print(b),
+		percentToken{precision: 1}.print(b),
+		" (2/4) ",
+	)
+
+	if got := b.Render(); got != expected || got != " [=>  ] 50.0% (2/4) " {
+		t.Errorf("Render()\n\n  got %#v\n  want %#v", got, expected)
+	}
+
+	if got := b.String(); got != expected {
+		t.Errorf("String()\n\n  got %#v\n  want %#v", got, expected)
+	}
+}
+
+func TestUpdateReusesTokens(t *testing.T) {
+	b := newTestBar(
+		newFakeClock(),
+		WithFormat(":bar :hello"),
+		WithContext(Context{Ctx("hello", "a")}),
+	)
+	format := b.format
+
+	b.TickAndUpdate(Context{Ctx("hello", "b")})
+	if &b.format[0] != &format[0] {
+		t.Error("TickAndUpdate tokenized the format again although its custom verbs didn't change")
+	}
+
+	if got, want := b.format[2], (customVerbToken{"hello"}); got != want {
+		t.Errorf("custom verb token\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	b.TickAndUpdate(Context{Ctx("world", "c")})
+	if got, want := b.format[2], (literalToken{":hello"}); got != want {
+		t.Errorf("token for a verb that is no longer defined\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	bar := newTestBar(newFakeClock(), WithDimensions(b.N+1, 40))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bar.progress = i
+		_ = bar.Render()
+	}
+}
+
+func BenchmarkTickAndUpdate(b *testing.B) {
+	bar := newTestBar(
+		newFakeClock(),
+		WithDimensions(b.N+1, 40),
+		WithFormat(" :bar :percent :hello "),
+		WithContext(Context{Ctx("hello", "world")}),
+	)
+	ctx := Context{Ctx("hello", "world")}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bar.TickAndUpdate(ctx)
+	}
+}
+
+func BenchmarkBarTokenPrint(b *testing.B) {
+	bar := newTestBar(newFakeClock(), WithDimensions(100, 80))
+	bar.progress = 37
+	tkn := barToken{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = tkn.print(bar)
+	}
+}
+
+func TestSmoothFill(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(32, 4), WithDisplay("[", "=", ">", " ", "]"), WithSmoothFill())
+
+	var testCases = []struct {
+		progress int
+		expected string
+	}{
+		{0, "[    ]"},
+		{1, "[▏   ]"},
+		{2, "[▎   ]"},
+		{8, "[█   ]"},
+		{17, "[██▏ ]"},
+		{20, "[██▌ ]"},
+		{22, "[██▊ ]"},
+		{31, "[███▉]"},
+		{32, "[████]"},
+	}
+
+	for i, testCase := range testCases {
+		b.progress = testCase.progress
+
+		if got := (barToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] smooth barToken.print at %d/32\n\n  got %#v\n  want %#v", i, testCase.progress, got, testCase.expected)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithFormat(":percent :eta :spinner"))
+
+	run := func() []string {
+		var frames []string
+		for i := 0; i < 10; i++ {
+			clock.advance(time.Second)
+			b.Tick()
+			frames = append(frames, b.Render())
+		}
+
+		b.Done()
+		return frames
+	}
+
+	first := run()
+
+	clock.advance(time.Minute)
+	b.Reset()
+
+	if got, want := b.Render(), "0.0% -- |"; got != want {
+		t.Errorf("render after reset\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	// the render above advanced the spinner, so start over once more
+	b.Reset()
+	second := run()
+
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("[%d] render after reset\n\n  got %#v\n  want %#v", i, second[i], first[i])
+		}
+	}
+}
+
+func TestSetTotal(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithFormat(":percent :count :eta"))
+
+	clock.advance(time.Second)
+	b.Tick()
+	clock.advance(time.Second)
+	b.Tick()
+
+	var testCases = []struct {
+		total    int
+		expected string
+	}{
+		// one item every two seconds so far
+		{20, "10.0% 2/20 36s"},
+		{4, "50.0% 2/4 4s"},
+		{1, "100.0% 1/1 0s"},
+	}
+
+	for i, testCase := range testCases {
+		b.SetTotal(testCase.total)
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%d] render after SetTotal(%d)\n\n  got %#v\n  want %#v", i, testCase.total, got, testCase.expected)
+		}
+	}
+}
+
+func TestRateSmoothing(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithDimensions(100, 10), WithRateSmoothing(0.2))
+
+	// items arrive in uneven bursts, but average out at 10 per second
+	for i := 0; i < 20; i++ {
+		clock.advance(10 * time.Millisecond)
+		b.Tick()
+
+		if i >= 10 && (b.rate < 8 || b.rate > 12) {
+			t.Errorf("[%d] rate after a short gap\n\n  got %v\n  want 10±2", i, b.rate)
+		}
+
+		clock.advance(190 * time.Millisecond)
+		b.Tick()
+
+		if i >= 10 && (b.rate < 8 || b.rate > 12) {
+			t.Errorf("[%d] rate after a long gap\n\n  got %v\n  want 10±2", i, b.rate)
+		}
+	}
+
+	if got, want := b.eta, time.Duration(float64(b.total-b.progress)/b.rate)*time.Second; got != want {
+		t.Errorf("eta from smoothed rate\n\n  got %v\n  want %v", got, want)
+	}
+}
+
+func TestSmoothedRateVerb(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithDimensions(100, 10), WithFormat(":rate :ratesmoothed(2,/s)"))
+
+	if got, want := b.Render(), "0.0 0.00/s"; got != want {
+		t.Errorf("render before any progress\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	clock.advance(time.Second)
+	b.Add(10)
+	clock.advance(time.Second)
+	b.Add(50)
+
+	// the rate is the average over the bar's lifetime (as of the previous
+	// update), while the smoothed rate leans towards the latest burst
+	if got, want := b.Render(), "5.0 31.05/s"; got != want {
+		t.Errorf("render after bursty progress\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if got, want := b.DebugString(), `<rateToken "5.0"> <smoothedRateToken "31.05/s">`; got != want {
+		t.Errorf("DebugString\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	// with rate smoothing, both verbs show the same smoothed rate
+	clock = newFakeClock()
+	b = newTestBar(clock, WithDimensions(100, 10), WithFormat(":rate :ratesmoothed"), WithRateSmoothing(0.5))
+	clock.advance(time.Second)
+	b.Add(10)
+
+	if got, want := b.Render(), "10.0 10.0"; got != want {
+		t.Errorf("render with rate smoothing\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestRateSmoothingRequiresFactorInRange(t *testing.T) {
+	for _, factor := range []float64{-0.5, 1.5} {
+		if _, err := TryNewWithOpts(WithDimensions(10, 10), WithRateSmoothing(factor)); err == nil {
+			t.Errorf("expected an error for a rate smoothing factor of %v", factor)
+		}
+	}
+}
+
+func TestFinishAt(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithFormat("finishes :finishat, :finishat(3:04:05 PM)"))
+
+	if got, want := b.Render(), "finishes --:--:--, --:--:--"; got != want {
+		t.Errorf("render before any progress\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	clock.advance(time.Second)
+	b.Tick()
+
+	if got, want := b.Render(), "finishes --:--:--, --:--:--"; got != want {
+		t.Errorf("render without a rate\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	clock.advance(time.Second)
+	b.Tick()
+	clock.advance(time.Second)
+	b.Tick()
+
+	// two items in three seconds leaves twelve seconds for the other eight
+	if got, want := b.Render(), "finishes 00:00:15, 12:00:15 AM"; got != want {
+		t.Errorf("render with a rate\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if eta, ok := b.ETA(); !ok || eta != 12*time.Second {
+		t.Errorf("ETA()\n\n  got %v, %v\n  want %v, %v", eta, ok, 12*time.Second, true)
+	}
+}
+
+func TestEtaWithoutRate(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithFormat(":eta :eta(mm:ss)"))
+
+	if got, want := b.Render(), "-- --"; got != want {
+		t.Errorf("eta of a new bar\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	// updates that don't make any progress leave the rate at zero
+	for i := 0; i < 3; i++ {
+		clock.advance(time.Second)
+		b.Update(0, nil)
+
+		if got, want := b.Render(), "-- --"; got != want {
+			t.Errorf("[%d] eta of a stalled bar\n\n  got %#v\n  want %#v", i, got, want)
+		}
+	}
+
+	// updates at the instant the bar started have no time to divide by
+	b = newTestBar(clock, WithFormat(":eta"))
+	b.Tick()
+	b.Tick()
+
+	if got, want := b.Render(), "--"; got != want {
+		t.Errorf("eta with no time elapsed\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if eta, ok := b.ETA(); ok || eta != 0 {
+		t.Errorf("ETA() with no time elapsed\n\n  got %v, %v\n  want %v, %v", eta, ok, time.Duration(0), false)
+	}
+}
+
+func TestETADone(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithDimensions(10, 4), WithFormat(":eta :eta(mm:ss)"), WithETADone("done"))
+
+	// one tick a second, up to just before the total
+	for i := 1; i < 10; i++ {
+		clock.advance(time.Second)
+		b.Tick()
+	}
+
+	if got, want := b.Render(), "2s 00:02"; got != want {
+		t.Errorf("eta at 9/10\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	clock.advance(time.Second)
+	b.Tick()
+
+	if got, want := b.Render(), "done done"; got != want {
+		t.Errorf("eta at 10/10\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	// without the option, the estimate is shown at the total too
+	b = newTestBar(clock, WithDimensions(10, 4), WithFormat(":eta"))
+	for i := 0; i < 10; i++ {
+		clock.advance(time.Second)
+		b.Tick()
+	}
+
+	if got := b.Render(); got == "done" || got == "--" {
+		t.Errorf("eta at the total without WithETADone isn't an estimate: %#v", got)
+	}
+}
+
+func TestPause(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithFormat(":elapsed :count :eta"))
+
+	clock.advance(time.Second)
+	b.Tick()
+	clock.advance(2 * time.Second)
+	b.Tick()
+
+	b.Pause()
+	clock.advance(time.Hour)
+
+	if got, want := b.Render(), "2s 2/10 27s"; got != want {
+		t.Errorf("render while paused\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	// pausing again doesn't restart the pause
+	b.Pause()
+	clock.advance(time.Hour)
+	b.Resume()
+	b.Resume()
+
+	clock.advance(time.Second)
+	b.Tick()
+
+	// two items in four seconds of work (excluding the two hours paused)
+	// leaves sixteen seconds for the other eight
+	if got, want := b.Render(), "3s 3/10 16s"; got != want {
+		t.Errorf("render after resuming\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestBarTokenClampsProgress(t *testing.T) {
+	var testCases = []struct {
+		progress int
+		expected string
+	}{
+		{-5, "[>    ]"},
+		{0, "[>    ]"},
+		{1, "[>    ]"},
+		{4, "[=>   ]"},
+		{10, "[====>]"},
+		{20, "[====>]"},
+	}
+
+	for _, smooth := range []bool{false, true} {
+		for i, testCase := range testCases {
+			b := newTestBar(newFakeClock(), WithDimensions(10, 5), WithDisplay("[", "=", ">", " ", "]"))
+			b.smooth = smooth
+			b.progress = testCase.progress
+
+			got := barToken{}.print(b)
+			if !smooth && got != testCase.expected {
+				t.Errorf("[%d] barToken.print with progress %d\n\n  got %#v\n  want %#v", i, testCase.progress, got, testCase.expected)
+			}
+
+			if width := displayWidth(got); width != 7 {
+				t.Errorf("[%d] width of bar with progress %d (smooth: %v)\n\n  got %d\n  want %d", i, testCase.progress, smooth, width, 7)
+			}
+		}
+	}
+}
+
+func TestCtxFunc(t *testing.T) {
+	files := []string{"a.txt", "b.txt", "c.txt"}
+
+	clock := newFakeClock()
+	b := newTestBar(clock, WithFormat(":count :file"), WithContext(Context{
+		CtxFunc("file", func(b *Bar) string {
+			return files[b.progress%len(files)]
+		}),
+	}))
+
+	for i, expected := range []string{"0/10 a.txt", "1/10 b.txt", "2/10 c.txt", "3/10 a.txt"} {
+		if got := b.Render(); got != expected {
+			t.Errorf("[%d] render with a custom verb function\n\n  got %#v\n  want %#v", i, got, expected)
+		}
+
+		b.Tick()
+	}
+}
+
+func TestSetCustomVerb(t *testing.T) {
+	ctx := Context{Ctx("status", "starting")}
+	b := newTestBar(newFakeClock(), WithFormat(":status :phase"), WithContext(ctx))
+
+	var testCases = []struct {
+		verb, value string
+		expected    string
+	}{
+		{"status", "downloading", "downloading :phase"},
+		{"status", "extracting", "extracting :phase"},
+		{"phase", "2/3", "extracting 2/3"},
+		{"unused", "x", "extracting 2/3"},
+	}
+
+	for i, testCase := range testCases {
+		b.SetCustomVerb(testCase.verb, testCase.value)
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%d] render after SetCustomVerb(%#v, %#v)\n\n  got %#v\n  want %#v", i, testCase.verb, testCase.value, got, testCase.expected)
+		}
+	}
+
+	if got := ctx[0].valueFor(b); got != "starting" {
+		t.Errorf("SetCustomVerb modified the context it was given\n\n  got %#v\n  want %#v", got, "starting")
+	}
+
+	if got, want := Context(b.context).customVerbs(), []string{"status", "phase"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("custom verbs after SetCustomVerb\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+// fileCount is a fmt.Stringer for testing custom verbs whose values change
+type fileCount struct{ n int }
+
+func (c *fileCount) String() string {
+	return fmt.Sprintf("%d files", c.n)
+}
+
+func TestAddVerb(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithFormat(":stage: :files"))
+	files := &fileCount{}
+
+	if got, want := b.Render(), ":stage: :files"; got != want {
+		t.Errorf("render before AddVerb\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	for _, err := range []error{
+		b.AddVerbString("stage", "copying"),
+		b.AddVerb("files", files),
+		b.AddVerbString("unused", "x"),
+	} {
+		if err != nil {
+			t.Fatalf("AddVerb returned unexpected error: %v", err)
+		}
+	}
+
+	if got, want := b.Render(), "copying: 0 files"; got != want {
+		t.Errorf("render after AddVerb\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	files.n = 3
+	if err := b.AddVerbString("stage", "verifying"); err != nil {
+		t.Fatalf("AddVerbString returned unexpected error: %v", err)
+	}
+
+	if got, want := b.Render(), "verifying: 3 files"; got != want {
+		t.Errorf("render after replacing a verb with AddVerbString\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if got, want := Context(b.context).customVerbs(), []string{"stage", "files", "unused"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("custom verbs after AddVerb\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	for _, verb := range []string{"", ":stage", "bar", "percent"} {
+		if err := b.AddVerbString(verb, "x"); err == nil {
+			t.Errorf("AddVerbString(%#v, _) returned no error", verb)
+		}
+	}
+
+	if err := b.AddVerb("files", nil); err == nil {
+		t.Error("AddVerb with a nil value returned no error")
+	}
+}
+
+func TestDirection(t *testing.T) {
+	var testCases = []struct {
+		direction Direction
+		head      string
+		progress  int
+		expected  string
+	}{
+		{LeftToRight, ">", 5, "[===>    ]"},
+		{RightToLeft, ">", 5, "[    <===]"},
+		{RightToLeft, "<", 5, "[    >===]"},
+		{RightToLeft, "=", 5, "[    ====]"},
+		{RightToLeft, ">", 0, "[       <]"},
+		{RightToLeft, ">", 10, "[<=======]"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(
+			newFakeClock(),
+			WithDimensions(10, 8),
+			WithDisplay("[", "=", testCase.head, " ", "]"),
+			WithDirection(testCase.direction),
+		)
+		b.progress = testCase.progress
+
+		if got := (barToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] barToken.print\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	var testCases = []struct {
+		progress int
+		smooth   bool
+		expected string
+	}{
+		{0, false, "[===>]"},
+		{5, false, "[=>  ]"},
+		{10, false, "[    ]"},
+		{0, true, "[████]"},
+		{5, true, "[██  ]"},
+		{10, true, "[    ]"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", ">", " ", "]"), WithCountdown())
+		b.smooth = testCase.smooth
+		b.progress = testCase.progress
+
+		if got := (barToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] barToken.print\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+}
+
+func TestOnRender(t *testing.T) {
+	var seen []int
+	b := newTestBar(newFakeClock(), WithFormat(":count"), WithOnRender(func(s Snapshot) {
+		seen = append(seen, s.Progress)
+	}))
+
+	b.Tick()
+	b.Add(2)
+	b.Set(7)
+	b.Render()
+
+	if want := []int{1, 3, 7, 7}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("progress seen by OnRender\n\n  got %#v\n  want %#v", seen, want)
+	}
+}
+
+func TestOnFinish(t *testing.T) {
+	var calls []int
+	b := newTestBar(newFakeClock(), WithDimensions(3, 3), WithOnFinish(func(s Snapshot) {
+		calls = append(calls, s.Progress)
+	}))
+
+	b.Tick()
+	b.Tick()
+
+	if len(calls) != 0 {
+		t.Errorf("OnFinish was called before completion with progress %v", calls)
+	}
+
+	b.Tick()
+	b.Set(3)
+	b.Finish()
+
+	if want := []int{3}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("OnFinish calls\n\n  got %#v\n  want %#v", calls, want)
+	}
+
+	// finishing early calls the hook too, and resetting allows it again
+	b.Reset()
+	b.Tick()
+	b.Done()
+
+	if want := []int{3, 1}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("OnFinish calls after reset\n\n  got %#v\n  want %#v", calls, want)
+	}
+
+	// lowering the total to the progress made completes the bar
+	b.Reset()
+	b.Tick()
+	b.Tick()
+	b.SetTotal(1)
+
+	if want := []int{3, 1, 1}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("OnFinish calls after lowering the total\n\n  got %#v\n  want %#v", calls, want)
+	}
+}
+
+func TestOnFinishCallsBar(t *testing.T) {
+	var b *Bar
+	var calls []int
+	hook := func(s Snapshot) {
+		b.ETA()
+		calls = append(calls, s.Progress)
+
+		// start on the next batch
+		b.Reset()
+		b.SetTotal(s.Total * 2)
+	}
+
+	callbacks := 0
+	b = newTestBar(newFakeClock(), WithDimensions(2, 4), WithOnFinish(hook), WithCallback(func() {
+		callbacks++
+		b.Percent()
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		b.Tick()
+		b.Tick()
+		b.Add(4)
+		b.Done()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hooks calling into the bar deadlocked")
+	}
+
+	// each reset lets the hook be called again, including by Done
+	if want := []int{2, 4, 0}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("OnFinish calls\n\n  got %#v\n  want %#v", calls, want)
+	}
+
+	if got, want := b.total, 16; got != want {
+		t.Errorf("total set by OnFinish\n\n  got %d\n  want %d", got, want)
+	}
+
+	if callbacks != 1 {
+		t.Errorf("callback was called %d times, want 1", callbacks)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	var testCases = []struct {
+		progress, total int
+		expected        float64
+	}{
+		{-5, 10, 0},
+		{0, 10, 0},
+		{3, 12, 0.25},
+		{10, 10, 1},
+		{25, 10, 1},
+		{5, 0, 0},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(testCase.total, 10))
+		b.progress = testCase.progress
+
+		if got := b.Percent(); got != testCase.expected {
+			t.Errorf("[%d] Percent() with progress %d of %d\n\n  got %v\n  want %v", i, testCase.progress, testCase.total, got, testCase.expected)
+		}
+	}
+}
+
+func TestRateWindow(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithDimensions(100, 10), WithRateWindow(2*time.Second))
+
+	// one item per second for twenty seconds...
+	for i := 0; i < 20; i++ {
+		clock.advance(time.Second)
+		b.Tick()
+	}
+
+	if b.rate < 0.9 || b.rate > 1.1 {
+		t.Errorf("rate at one item per second\n\n  got %v\n  want 1±0.1", b.rate)
+	}
+
+	// ...then ten per second for five more
+	for i := 0; i < 50; i++ {
+		clock.advance(100 * time.Millisecond)
+		b.Tick()
+	}
+
+	if b.rate < 9 || b.rate > 11 {
+		t.Errorf("rate after speeding up\n\n  got %v\n  want 10±1 (the average is %v)", b.rate, 70.0/25)
+	}
+
+	if eta, ok := b.ETA(); !ok || eta != 3*time.Second {
+		t.Errorf("ETA after speeding up\n\n  got %v, %v\n  want %v, %v", eta, ok, 3*time.Second, true)
+	}
+}
+
+func TestRateWindowWithManyUpdates(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithDimensions(100000, 10), WithRateWindow(time.Second))
+
+	// many more updates than samples within the window
+	for i := 0; i < 10000; i++ {
+		clock.advance(time.Millisecond)
+		b.Tick()
+	}
+
+	if b.rate < 900 || b.rate > 1100 {
+		t.Errorf("rate at a thousand items per second\n\n  got %v\n  want 1000±100", b.rate)
+	}
+}
+
+func TestRateWindowRejectsSmoothing(t *testing.T) {
+	if _, err := TryNewWithOpts(WithRateWindow(time.Second), WithRateSmoothing(0.5)); err == nil {
+		t.Error("expected an error combining WithRateWindow and WithRateSmoothing")
+	}
+}
+
+func TestPercentInBar(t *testing.T) {
+	var testCases = []struct {
+		width, progress int
+		smooth          bool
+		expected        string
+	}{
+		{14, 50, false, "[==== 50%      ]"},
+		{15, 45, false, "[===== 45%      ]"},
+		{14, 100, false, "[==== 100% ===>]"},
+		{14, 0, false, "[>     0%      ]"},
+		{4, 50, false, "[=>  ]"},
+		{8, 50, true, "[█ 50%   ]"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(100, testCase.width), WithDisplay("[", "=", ">", " ", "]"), WithPercentInBar())
+		b.smooth = testCase.smooth
+		b.progress = testCase.progress
+
+		got := (barToken{}).print(b)
+		if got != testCase.expected {
+			t.Errorf("[%d] barToken.print with the percentage in the bar\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+
+		if width := displayWidth(got); width != testCase.width+2 {
+			t.Errorf("[%d] width of bar with the percentage in it\n\n  got %d\n  want %d", i, width, testCase.width+2)
+		}
+	}
+}
+
+func TestDisplayPatterns(t *testing.T) {
+	var testCases = []struct {
+		width, progress int
+		opts            []Option
+		expected        string
+	}{
+		{4, 0, []Option{WithIncompletePattern("·-")}, "[>-·-]"},
+		{5, 0, []Option{WithIncompletePattern("·-")}, "[>-·-·]"},
+		{7, 0, []Option{WithIncompletePattern("·-")}, "[>-·-·-·]"},
+		{5, 4, []Option{WithIncompletePattern("·-")}, "[=>·-·]"},
+		{6, 5, []Option{WithIncompletePattern("·-")}, "[==>-·-]"},
+		{5, 10, []Option{WithIncompletePattern("·-")}, "[====>]"},
+		{5, 0, []Option{WithIncompletePattern("·-"), WithHiddenHeadAtZero()}, "[·-·-·]"},
+		{6, 5, []Option{WithCompletePattern("=~")}, "[=~>   ]"},
+		{6, 10, []Option{WithCompletePattern("=~"), WithIncompletePattern("·-")}, "[=~=~=>]"},
+		{6, 5, []Option{WithIncompletePattern("·-"), WithDirection(RightToLeft)}, "[·-·<==]"},
+		{6, 5, []Option{WithCompletePattern("=~"), WithDirection(RightToLeft)}, "[   <~=]"},
+		{6, 5, []Option{WithIncompletePattern("·-"), WithSmoothFill()}, "[███-·-]"},
+		{5, 0, []Option{WithIncompletePattern("·-"), WithDimensions(0, 5)}, "[=-·-·]"},
+		{10, 4, []Option{WithDisplay("[", "=", ">", "·-", "]")}, "[===>·-·-·-]"},
+		{6, 10, []Option{WithDisplay("[", "=~", "", " ", "]")}, "[=~=~==]"},
+		{5, 0, []Option{WithIncompletePattern("あ")}, "[> あ ]"},
+		{6, 5, []Option{WithCompletePattern("あ")}, "[あ>   ]"},
+		{6, 5, []Option{WithCompletePattern("あ"), WithDirection(RightToLeft)}, "[   <あ]"},
+		{10, 0, []Option{WithIncompletePattern("あ"), WithPercentInBar()}, "[>   0%  あ]"},
+		{5, 0, []Option{WithDisplay("[", "あ", ">", " ", "]"), WithDimensions(0, 5)}, "[あ   ]"},
+		{5, 0, []Option{WithIncompletePattern("e\u0301")}, "[>e\u0301e\u0301e\u0301e\u0301]"},
+	}
+
+	for i, testCase := range testCases {
+		opts := append([]Option{
+			WithDimensions(10, testCase.width),
+			WithDisplay("[", "=", ">", " ", "]"),
+			WithFormat(":bar"),
+		}, testCase.opts...)
+		b := newTestBar(newFakeClock(), opts...)
+		b.progress = testCase.progress
+
+		got := b.Render()
+		if got != testCase.expected {
+			t.Errorf("[%d] bar with patterns at %d/10\n\n  got %#v\n  want %#v", i, testCase.progress, got, testCase.expected)
+		}
+
+		if width := displayWidth(got) - 2; width != testCase.width {
+			t.Errorf("[%d] bar with patterns is %d cells wide, want %d", i, width, testCase.width)
+		}
+	}
+}
+
+func TestWithoutCaps(t *testing.T) {
+	var testCases = []struct {
+		format   string
+		opts     []Option
+		progress int
+		expected string
+	}{
+		{":bar", nil, 0, ">     "},
+		{":bar", nil, 5, "==>   "},
+		{":bar", nil, 10, "=====>"},
+		{":bar :count", nil, 5, "==>    5/10"},
+		{":bar(3)", nil, 5, ">  "},
+		{":bar", []Option{WithSmoothFill()}, 5, "███   "},
+		{":bar", []Option{WithPercentInBar()}, 5, " 50%  "},
+		{":bar", []Option{WithDimensions(0, 6)}, 0, "=     "},
+	}
+
+	for i, testCase := range testCases {
+		opts := append([]Option{
+			WithDimensions(10, 6),
+			WithDisplay("[", "=", ">", " ", "]"),
+			WithFormat(testCase.format),
+		}, testCase.opts...)
+		b := newTestBar(newFakeClock(), append(opts, WithoutCaps())...)
+		b.progress = testCase.progress
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%d] %#v without caps at %d\n\n  got %#v\n  want %#v", i, testCase.format, testCase.progress, got, testCase.expected)
+		}
+	}
+
+	// the caps are left out even if WithDisplay comes after
+	b := newTestBar(newFakeClock(), WithoutCaps(), WithDimensions(10, 6), WithFormat(":bar"), WithDisplay("[", "=", ">", " ", "]"))
+	b.progress = 5
+	if got, want := b.Render(), "==>   "; got != want {
+		t.Errorf("bar without caps before WithDisplay\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	// the caps don't count towards a bar fit to the terminal
+	b = newTestBar(newFakeClock(), WithDimensions(10, 6), WithDisplay("[", "=", ">", " ", "]"), WithFormat(":bar :count"), WithFitWidth(), WithoutCaps())
+	b.progress = 5
+	b.termWidth = func() (int, bool) {
+		return 15, true
+	}
+
+	if got, want := b.Render(), "====>      5/10"; got != want {
+		t.Errorf("fitted bar without caps\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestHeadFrames(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", ">", " ", "]"), WithHeadFrames(">", "»", "›"), WithFormat(":bar"))
+	b.progress = 5
+
+	for i, expected := range []string{"[=>  ]", "[=»  ]", "[=›  ]", "[=>  ]"} {
+		if got := b.render(); got != expected {
+			t.Errorf("[%d] render with head frames\n\n  got %#v\n  want %#v", i, got, expected)
+		}
+	}
+
+	b.progress = 0
+	if got, want := b.render(), "[»   ]"; got != want {
+		t.Errorf("render with head frames at 0%%\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if _, err := TryNewWithOpts(WithHeadFrames(">", "")); err == nil {
+		t.Error("TryNewWithOpts with an empty head frame didn't return an error")
+	}
+}
+
+func TestHiddenHeadWhenFull(t *testing.T) {
+	var testCases = []struct {
+		progress  int
+		countdown bool
+		expected  string
+	}{
+		{0, false, "[>   ]"},
+		{5, false, "[=>  ]"},
+		{9, false, "[==> ]"},
+		{10, false, "[====]"},
+		{12, false, "[====]"},
+		{0, true, "[===>]"},
+		{10, true, "[    ]"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", ">", " ", "]"), WithHiddenHeadWhenFull())
+		b.countdown = testCase.countdown
+		b.progress = testCase.progress
+
+		if got := (barToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] barToken.print with the head hidden when full\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+}
+
+func TestDebugString(t *testing.T) {
+	b := newTestBar(
+		newFakeClock(),
+		WithDimensions(10, 4),
+		WithFormat(":bar :percent :hello done"),
+		WithContext(Context{Ctx("hello", "Hello!")}),
+	)
+	b.progress = 4
+
+	expected := `<barToken p={4} t={10}> <percentToken "40.0%"> <customVerbToken verb="hello" value="Hello!"> <literalToken "done">`
+	if got := b.DebugString(); got != expected {
+		t.Errorf("DebugString\n\n  got %#v\n  want %#v", got, expected)
+	}
+
+	if b.frame != 0 {
+		t.Errorf("DebugString advanced the bar's frame to %d", b.frame)
+	}
+}
+
+// slowStringer takes a while to produce its value
+type slowStringer time.Duration
+
+func (s slowStringer) String() string {
+	time.Sleep(time.Duration(s))
+	return "slow"
+}
+
+func TestLastRenderDuration(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithFormat(":slow"), WithRenderTiming())
+	if err := b.AddVerb("slow", slowStringer(20*time.Millisecond)); err != nil {
+		t.Fatalf("AddVerb returned an error: %v", err)
+	}
+
+	if got := b.LastRenderDuration(); got != 0 {
+		t.Errorf("LastRenderDuration before rendering\n\n  got %v\n  want %v", got, time.Duration(0))
+	}
+
+	b.Render()
+	if got := b.LastRenderDuration(); got < 20*time.Millisecond {
+		t.Errorf("LastRenderDuration with a slow custom verb\n\n  got %v\n  want at least %v", got, 20*time.Millisecond)
+	}
+
+	// without the option, renders aren't timed
+	b = newTestBar(newFakeClock(), WithFormat(":slow"))
+	b.AddVerb("slow", slowStringer(time.Millisecond))
+	b.Render()
+
+	if got := b.LastRenderDuration(); got != 0 {
+		t.Errorf("LastRenderDuration without WithRenderTiming\n\n  got %v\n  want %v", got, time.Duration(0))
+	}
+}
+
+func TestRange(t *testing.T) {
+	var indices []int
+	b := newTestBar(newFakeClock(), WithDimensions(5, 5))
+	b.Range(5, func(i int) {
+		if got, want := b.progress, i; got != want {
+			t.Errorf("progress before calling fn(%d)\n\n  got %d\n  want %d", i, got, want)
+		}
+
+		indices = append(indices, i)
+	})
+
+	if want := []int{0, 1, 2, 3, 4}; !reflect.DeepEqual(indices, want) {
+		t.Errorf("indices passed to fn\n\n  got %#v\n  want %#v", indices, want)
+	}
+
+	if got := b.Percent(); got != 1 {
+		t.Errorf("Percent after Range\n\n  got %v\n  want %v", got, 1.0)
+	}
+
+	if !b.closed {
+		t.Errorf("bar wasn't finished after Range")
+	}
+
+	// the bar is finished even if fn is never called
+	b = newTestBar(newFakeClock(), WithDimensions(5, 5))
+	b.Range(0, func(i int) {
+		t.Errorf("fn(%d) called by Range(0, fn)", i)
+	})
+
+	if !b.closed || b.progress != 5 {
+		t.Errorf("bar after Range(0, fn)\n\n  got closed=%v, progress=%d\n  want closed=true, progress=5", b.closed, b.progress)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	b := newTestBar(
+		newFakeClock(),
+		WithDimensions(10, 4),
+		WithFormat("eta: :eta :bar(6)\t:percent(0) :hello"),
+		WithContext(Context{Ctx("hello", "Hello!")}),
+	)
+
+	expected := []string{
+		`<literalToken "eta">`,
+		`<literalToken ": ">`,
+		`<etaToken "--">`,
+		" ",
+		`<barToken p={0} t={10}>`,
+		"\t",
+		`<percentToken "0%">`,
+		" ",
+		`<customVerbToken verb="hello" value="Hello!">`,
+	}
+
+	if got := b.Tokens(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Tokens\n\n  got %#v\n  want %#v", got, expected)
+	}
+
+	if b.frame != 0 {
+		t.Errorf("Tokens advanced the bar's frame to %d", b.frame)
+	}
+}
+
+func TestConsistentRounding(t *testing.T) {
+	var testCases = []struct {
+		total, width, progress int
+		precision              int
+		percent                string
+		cells                  int
+	}{
+		{10000, 10, 9999, 1, "99.9%", 9},
+		{10000, 10, 10000, 1, "100.0%", 10},
+		{1000, 10, 995, 0, "99%", 9},
+		{1000, 10, 199, 1, "19.9%", 1},
+		{1000, 10, 200, 1, "20.0%", 2},
+		{100, 100, 29, 0, "29%", 29},
+		{100, 100, 57, 2, "57.00%", 57},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(testCase.total, testCase.width), WithConsistentRounding())
+		b.progress = testCase.progress
+
+		if got := (percentToken{precision: testCase.precision}).print(b); got != testCase.percent {
+			t.Errorf("[%d] percentToken.print with consistent rounding\n\n  got %#v\n  want %#v", i, got, testCase.percent)
+		}
+
+		if got := b.cells(testCase.width); got != testCase.cells {
+			t.Errorf("[%d] cells with consistent rounding\n\n  got %d\n  want %d", i, got, testCase.cells)
+		}
+	}
+
+	// without it, the percentage is rounded to nearest and reads 100% before
+	// the bar is full
+	b := newTestBar(newFakeClock(), WithDimensions(10000, 10))
+	b.progress = 9999
+
+	if got, cells := (percentToken{precision: 1}).print(b), b.cells(10); got != "100.0%" || cells != 9 {
+		t.Errorf("percentToken.print and cells without consistent rounding\n\n  got %#v, %d\n  want %#v, %d", got, cells, "100.0%", 9)
+	}
+}
+
+func TestHiddenHeadAtZero(t *testing.T) {
+	var testCases = []struct {
+		progress  int
+		hide      bool
+		direction Direction
+		expected  string
+	}{
+		{0, false, LeftToRight, "[>   ]"},
+		{0, true, LeftToRight, "[    ]"},
+		{2, true, LeftToRight, "[    ]"},
+		{3, true, LeftToRight, "[>   ]"},
+		{5, true, LeftToRight, "[=>  ]"},
+		{10, true, LeftToRight, "[===>]"},
+		{0, false, RightToLeft, "[   <]"},
+		{0, true, RightToLeft, "[    ]"},
+	}
+
+	for i, testCase := range testCases {
+		opts := []Option{WithDimensions(10, 4), WithDisplay("[", "=", ">", " ", "]"), WithDirection(testCase.direction)}
+		if testCase.hide {
+			opts = append(opts, WithHiddenHeadAtZero())
+		}
+
+		b := newTestBar(newFakeClock(), opts...)
+		b.progress = testCase.progress
+
+		got := (barToken{}).print(b)
+		if got != testCase.expected {
+			t.Errorf("[%d] barToken.print with the head hidden at zero\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+
+		if width := displayWidth(got); width != 6 {
+			t.Errorf("[%d] width of bar with the head hidden at zero\n\n  got %d\n  want %d", i, width, 6)
+		}
+	}
+}
+
+func TestPrefixAndSuffix(t *testing.T) {
+	var testCases = []struct {
+		format   string
+		opts     []Option
+		expected string
+	}{
+		{":count", []Option{WithPrefix("step 1: ")}, "step 1: 5/10"},
+		{":count", []Option{WithSuffix(" :bar (50%)")}, "5/10 :bar (50%)"},
+		{":bar", []Option{WithPrefix(":count "), WithSuffix(" :percent")}, ":count [==>   ] :percent"},
+		{"", []Option{WithPrefix("a:"), WithSuffix(":b")}, "a::b"},
+		{":bar :count", []Option{WithPrefix("下载: "), WithFitWidth()}, "下载: [===>     ] 5/10"},
+		{":count:fill", []Option{WithPrefix("x: "), WithSuffix(" :y")}, "x: 5/10             :y"},
+	}
+
+	for i, testCase := range testCases {
+		opts := append([]Option{
+			WithDimensions(10, 6),
+			WithDisplay("[", "=", ">", " ", "]"),
+			WithFormat(testCase.format),
+		}, testCase.opts...)
+		b := newTestBar(newFakeClock(), opts...)
+		b.progress = 5
+		b.termWidth = func() (int, bool) {
+			return 22, true
+		}
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%d] %#v with a prefix and suffix\n\n  got %#v\n  want %#v", i, testCase.format, got, testCase.expected)
+		}
+	}
+}
+
+func TestNarrowBars(t *testing.T) {
+	var testCases = []struct {
+		width, progress int
+		opts            []Option
+		expected        string
+	}{
+		{1, 0, nil, "[>]"},
+		{1, 5, nil, "[>]"},
+		{1, 10, nil, "[=]"},
+		{2, 0, nil, "[> ]"},
+		{2, 5, nil, "[> ]"},
+		{2, 10, nil, "[=>]"},
+		{3, 0, nil, "[>  ]"},
+		{3, 5, nil, "[>  ]"},
+		{3, 10, nil, "[==>]"},
+		{1, 0, []Option{WithHiddenHeadAtZero()}, "[ ]"},
+		{1, 10, []Option{WithDirection(RightToLeft)}, "[=]"},
+		{2, 10, []Option{WithDirection(RightToLeft)}, "[<=]"},
+		{1, 0, []Option{WithSmoothFill()}, "[ ]"},
+		{1, 5, []Option{WithSmoothFill()}, "[▌]"},
+		{1, 10, []Option{WithSmoothFill()}, "[█]"},
+		{2, 5, []Option{WithSmoothFill()}, "[█ ]"},
+		{3, 5, []Option{WithSmoothFill()}, "[█▌ ]"},
+		{1, 5, []Option{WithPercentInBar()}, "[>]"},
+		{1, 5, []Option{WithDimensions(0, 1)}, "[=]"},
+		{2, 5, []Option{WithDimensions(0, 2)}, "[= ]"},
+	}
+
+	for i, testCase := range testCases {
+		opts := append([]Option{
+			WithDimensions(10, testCase.width),
+			WithDisplay("[", "=", ">", " ", "]"),
+			WithFormat(":bar"),
+		}, testCase.opts...)
+		b := newTestBar(newFakeClock(), opts...)
+		b.progress = testCase.progress
+
+		got := b.Render()
+		if got != testCase.expected {
+			t.Errorf("[%d] %d-cell bar at %d/10\n\n  got %#v\n  want %#v", i, testCase.width, testCase.progress, got, testCase.expected)
+		}
+
+		if width := displayWidth(got) - 2; width != testCase.width {
+			t.Errorf("[%d] %d-cell bar is %d cells wide", i, testCase.width, width)
+		}
+	}
+
+	// a bar can't be narrower than a single cell
+	if _, err := TryNewWithOpts(WithDimensions(10, 0)); err == nil {
+		t.Error("expected an error for a bar with no cells")
+	}
+}
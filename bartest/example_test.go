@@ -0,0 +1,76 @@
+package bartest_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/superhawk610/bar"
+	"github.com/superhawk610/bar/bartest"
+)
+
+func ExampleRecorder() {
+	r := bartest.NewRecorder()
+
+	b := bar.NewWithOpts(
+		bar.WithDimensions(4, 8),
+		bar.WithDisplay("[", "=", ">", " ", "]"),
+		bar.WithFormat(":bar :count"),
+		bar.WithWriter(r),
+		bar.WithTTY(true),
+	)
+
+	b.Tick()
+	b.Tick()
+	fmt.Println(r.Line())
+
+	b.Finish()
+	fmt.Println(r.Line())
+	// Output:
+	// [===>    ] 2/4
+	// [=======>] 4/4
+}
+
+func TestRecorderWithGroup(t *testing.T) {
+	r := bartest.NewRecorder()
+	g := bar.NewGroup(r)
+
+	bars := make([]*bar.Bar, 2)
+	for i := range bars {
+		bars[i] = bar.NewWithOpts(
+			bar.WithDimensions(4, 4),
+			bar.WithDisplay("[", "=", ">", " ", "]"),
+			bar.WithFormat(":bar :count"),
+			bar.WithTTY(true),
+		)
+		g.Add(bars[i])
+	}
+
+	bars[1].Set(4)
+	bars[0].Tick()
+	bars[0].Interrupt("hello")
+	bars[0].Tick()
+	g.Stop()
+
+	want := []string{
+		"hello",
+		"[=>  ] 2/4",
+		"[===>] 4/4",
+	}
+
+	if got := r.Screen(); !reflect.DeepEqual(got, want) {
+		t.Errorf("group drawn to a recorder\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestRecorderAfterClear(t *testing.T) {
+	r := bartest.NewRecorder()
+
+	b := bar.NewWithOpts(bar.WithDimensions(4, 4), bar.WithWriter(r), bar.WithTTY(true))
+	b.Tick()
+	b.Clear()
+
+	if got := r.Line(); got != "" {
+		t.Errorf("line after clearing the bar\n\n  got %#v\n  want %#v", got, "")
+	}
+}
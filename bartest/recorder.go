@@ -0,0 +1,235 @@
+// Package bartest provides helpers for testing programs that draw progress
+// bars, such as a Recorder that models the screen of a terminal so that the
+// result of a sequence of updates can be asserted on.
+package bartest
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// tabWidth is the distance between the terminal's tab stops
+const tabWidth = 8
+
+// Recorder is an io.Writer that records everything written to it, applying
+// the control sequences used by bars (carriage returns, newlines, erasing
+// lines and moving the cursor up and down) to an in-memory screen the way a
+// terminal would. Other escape sequences, such as colors, are recorded but
+// don't affect the screen, and every character takes up a single column.
+// Since a Recorder isn't a terminal itself, bars drawn to one should be
+// created with bar.WithTTY(true).
+//
+// A Recorder is safe for concurrent use. The zero value is an empty screen
+// with the cursor in its top left corner.
+type Recorder struct {
+	mu       sync.Mutex
+	raw      bytes.Buffer
+	pending  []byte
+	lines    [][]rune
+	row, col int
+}
+
+// NewRecorder creates a new, empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Write records p and applies it to the screen; it never returns an error.
+// Escape sequences and characters may be split across several writes.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.raw.Write(p)
+	r.pending = append(r.pending, p...)
+
+	buf := r.pending
+	for len(buf) > 0 {
+		n := r.apply(buf)
+		if n == 0 {
+			break
+		}
+
+		buf = buf[n:]
+	}
+
+	// keep whatever is left of an incomplete sequence for the next write
+	r.pending = append(r.pending[:0], buf...)
+
+	return len(p), nil
+}
+
+// apply applies the character or escape sequence at the start of buf to the
+// screen, returning the number of bytes it used, or 0 if buf ends before it
+// does; the caller must hold r.mu
+func (r *Recorder) apply(buf []byte) int {
+	switch buf[0] {
+	case '\r':
+		r.col = 0
+		return 1
+	case '\n':
+		// terminals usually translate newlines into a carriage return too
+		r.row, r.col = r.row+1, 0
+		return 1
+	case '\t':
+		r.col = (r.col/tabWidth + 1) * tabWidth
+		return 1
+	case '\x1b':
+		return r.escape(buf)
+	}
+
+	if !utf8.FullRune(buf) {
+		return 0
+	}
+
+	c, size := utf8.DecodeRune(buf)
+	r.put(c)
+
+	return size
+}
+
+// escape applies the escape sequence at the start of buf, returning its
+// length, or 0 if it's incomplete; the caller must hold r.mu
+func (r *Recorder) escape(buf []byte) int {
+	if len(buf) < 2 {
+		return 0
+	}
+
+	// anything other than a control sequence is ignored
+	if buf[1] != '[' {
+		return 2
+	}
+
+	// a control sequence is made of parameters, then a final byte
+	end := 2
+	for end < len(buf) && (buf[end] < 0x40 || buf[end] > 0x7e) {
+		end++
+	}
+
+	if end == len(buf) {
+		return 0
+	}
+
+	// a missing parameter leaves n at 0, which is the default for erasing
+	// and means a single line for moving the cursor
+	n, _ := strconv.Atoi(string(buf[2:end]))
+
+	switch buf[end] {
+	case 'A':
+		if n == 0 {
+			n = 1
+		}
+
+		r.row -= n
+		if r.row < 0 {
+			r.row = 0
+		}
+	case 'B':
+		if n == 0 {
+			n = 1
+		}
+
+		r.row += n
+	case 'K':
+		r.eraseLine(n)
+	}
+
+	return end + 1
+}
+
+// put writes c at the cursor, moving it one column to the right; the caller
+// must hold r.mu
+func (r *Recorder) put(c rune) {
+	line := r.line()
+	for len(line) <= r.col {
+		line = append(line, ' ')
+	}
+
+	line[r.col] = c
+	r.lines[r.row] = line
+	r.col++
+}
+
+// eraseLine erases the line the cursor is on from the cursor to its end
+// (mode 0), from its start to the cursor (mode 1), or entirely (mode 2),
+// without moving the cursor; the caller must hold r.mu
+func (r *Recorder) eraseLine(mode int) {
+	line := r.line()
+
+	switch mode {
+	case 0:
+		if r.col < len(line) {
+			line = line[:r.col]
+		}
+	case 1:
+		for i := 0; i <= r.col && i < len(line); i++ {
+			line[i] = ' '
+		}
+	case 2:
+		line = line[:0]
+	}
+
+	r.lines[r.row] = line
+}
+
+// line returns the line the cursor is on, adding empty lines to the screen
+// until it's long enough; the caller must hold r.mu
+func (r *Recorder) line() []rune {
+	for len(r.lines) <= r.row {
+		r.lines = append(r.lines, nil)
+	}
+
+	return r.lines[r.row]
+}
+
+// Raw returns everything written to the recorder, including any control
+// sequences
+func (r *Recorder) Raw() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.raw.String()
+}
+
+// Screen returns each line of the screen as it would be displayed, without
+// trailing spaces; trailing empty lines (such as the one left below a
+// finished bar) are left out
+func (r *Recorder) Screen() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lines := make([]string, len(r.lines))
+	last := 0
+	for i, line := range r.lines {
+		lines[i] = strings.TrimRight(string(line), " ")
+		if lines[i] != "" {
+			last = i + 1
+		}
+	}
+
+	return lines[:last]
+}
+
+// Line returns the last line of the screen that isn't empty, which is the
+// bar itself when a single bar is drawn to the recorder
+func (r *Recorder) Line() string {
+	screen := r.Screen()
+	if len(screen) == 0 {
+		return ""
+	}
+
+	return screen[len(screen)-1]
+}
+
+// String returns the lines of the screen, each ending in a newline
+func (r *Recorder) String() string {
+	var sb strings.Builder
+	for _, line := range r.Screen() {
+		sb.WriteString(line + "\n")
+	}
+
+	return sb.String()
+}
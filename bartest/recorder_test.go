@@ -0,0 +1,64 @@
+package bartest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	var testCases = []struct {
+		writes []string
+		screen []string
+	}{
+		{[]string{"hello"}, []string{"hello"}},
+		{[]string{"hello\rj"}, []string{"jello"}},
+		{[]string{"a\nb\n"}, []string{"a", "b"}},
+		{[]string{"hello\r\x1b[2Kbye"}, []string{"bye"}},
+		{[]string{"hello\r\x1b[2K"}, nil},
+		{[]string{"hello\rhe\x1b[K"}, []string{"he"}},
+		{[]string{"hello\rhe\x1b[1K"}, []string{"   lo"}},
+		{[]string{"a\nb\nc\n\x1b[2Ax"}, []string{"a", "x", "c"}},
+		{[]string{"a\x1b[5Ab"}, []string{"ab"}},
+		{[]string{"a\x1b[Bb"}, []string{"a", " b"}},
+		{[]string{"a\tb"}, []string{"a       b"}},
+		{[]string{"\x1b[32mok\x1b[0m"}, []string{"ok"}},
+		{[]string{"下载 🎉"}, []string{"下载 🎉"}},
+		{[]string{"hello\r\x1b", "[2", "Kbye"}, []string{"bye"}},
+		{[]string{"\xe4\xb8", "\x8b"}, []string{"下"}},
+	}
+
+	for i, testCase := range testCases {
+		r := NewRecorder()
+		raw := ""
+		for _, w := range testCase.writes {
+			if n, err := r.Write([]byte(w)); n != len(w) || err != nil {
+				t.Errorf("[%d] Write(%#v) = %d, %v", i, w, n, err)
+			}
+			raw += w
+		}
+
+		if got := r.Screen(); !reflect.DeepEqual(got, testCase.screen) && !(len(got) == 0 && len(testCase.screen) == 0) {
+			t.Errorf("[%d] screen after writing %#v\n\n  got %#v\n  want %#v", i, testCase.writes, got, testCase.screen)
+		}
+
+		if got := r.Raw(); got != raw {
+			t.Errorf("[%d] raw output\n\n  got %#v\n  want %#v", i, got, raw)
+		}
+	}
+}
+
+func TestRecorderLine(t *testing.T) {
+	var r Recorder
+	if got := r.Line(); got != "" {
+		t.Errorf("line of an empty recorder\n\n  got %#v\n  want %#v", got, "")
+	}
+
+	r.Write([]byte("first\nsecond\n\n"))
+	if got, want := r.Line(), "second"; got != want {
+		t.Errorf("line of a recorder\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if got, want := r.String(), "first\nsecond\n"; got != want {
+		t.Errorf("recorder's screen\n\n  got %#v\n  want %#v", got, want)
+	}
+}
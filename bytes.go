@@ -0,0 +1,127 @@
+package bar
+
+import (
+	"fmt"
+	"time"
+)
+
+var (
+	decimalUnits = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+	binaryUnits  = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+)
+
+// formatBytes converts n into a human-readable string, using base-1024
+// (IEC) units when binary is true and base-1000 (SI) units otherwise
+func formatBytes(n float64, binary bool) string {
+	base, units := 1000.0, decimalUnits
+	if binary {
+		base, units = 1024.0, binaryUnits
+	}
+
+	// whole bytes are printed without decimals, so they only move up to the
+	// next unit once they would round to a full one
+	if n < base-0.5 {
+		return fmt.Sprintf("%.0f %s", n, units[0])
+	}
+
+	// advance while the value would round up to a full unit at one decimal
+	// place, so that we print `1.0 MB` rather than `1000.0 kB`
+	n /= base
+	exp := 1
+	for n >= base-0.05 && exp < len(units)-1 {
+		n /= base
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %s", n, units[exp])
+}
+
+// SetBytesTotal sets the number of bytes that the bar's work amounts to. From
+// then on, :eta and :finishat (as well as ETA) are estimated from the bytes
+// recorded with AddBytes and SetBytes rather than from the bar's progress, so
+// that they're accurate even when items vary in size, such as when the bar
+// counts files being copied. It doesn't redraw the bar.
+func (b *Bar) SetBytesTotal(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.canUpdate("SetBytesTotal") {
+		return
+	}
+
+	b.countBytes = true
+	b.bytesTotal = n
+}
+
+// AddBytes records that another n bytes have been processed and redraws the
+// bar; from then on, :bytes and :speed show the bytes recorded this way rather
+// than the bar's progress. It is safe for concurrent use.
+func (b *Bar) AddBytes(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.canUpdate("AddBytes") {
+		return
+	}
+
+	b.updateBytes(b.bytesDone + n)
+}
+
+// SetBytes is like AddBytes, but sets the number of bytes processed to n
+func (b *Bar) SetBytes(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.canUpdate("SetBytes") {
+		return
+	}
+
+	b.updateBytes(n)
+}
+
+// updateBytes sets the number of bytes processed, measuring their rate the
+// same way as the bar's rate (over the rate window, smoothed, or since the
+// bar started), and redraws the bar; the caller must hold b.mu
+func (b *Bar) updateBytes(n int64) {
+	now := b.clock()
+	if b.started.IsZero() {
+		b.started = now
+	}
+
+	b.countBytes = true
+
+	// there's no rate until some time has passed to divide by
+	if b.rateWindow > 0 {
+		base := rateSample{b.startedAt, b.bytesDone}
+		if rate, ok := b.byteWindow.measure(base, rateSample{now, n}, b.rateWindow); ok {
+			b.byteRate = rate
+		}
+	} else if b.rateSmoothing > 0 {
+		if b.smoothedBytes.sample(b.startedAt, now, n, b.rateSmoothing) {
+			b.byteRate = b.smoothedBytes.rate()
+		}
+	} else if seconds := now.Sub(b.startedAt).Seconds(); seconds > 0 {
+		b.byteRate = float64(n) / seconds
+	}
+
+	b.bytesDone = n
+
+	if !b.throttled(b.now()) {
+		b.write()
+	}
+}
+
+// estimateBytes estimates the time remaining from the rate at which bytes
+// have been processed; the caller must hold b.mu
+func (b *Bar) estimateBytes() (time.Duration, bool) {
+	if b.byteRate <= 0 {
+		return 0, false
+	}
+
+	remaining := b.bytesTotal - b.bytesDone
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return time.Duration(float64(remaining)/b.byteRate) * time.Second, true
+}
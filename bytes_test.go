@@ -0,0 +1,198 @@
+package bar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	var testCases = []struct {
+		n        float64
+		binary   bool
+		expected string
+	}{
+		{0, false, "0 B"},
+		{999, false, "999 B"},
+		{999.4, false, "999 B"},
+		{999.7, false, "1.0 kB"},
+		{999.96, false, "1.0 kB"},
+		{512.4, false, "512 B"},
+		{1000, false, "1.0 kB"},
+		{1023, false, "1.0 kB"},
+		{1024, false, "1.0 kB"},
+		{1400000, false, "1.4 MB"},
+		{999999, false, "1.0 MB"},
+		{2.5e12, false, "2.5 TB"},
+		{999, true, "999 B"},
+		{1000, true, "1000 B"},
+		{1023, true, "1023 B"},
+		{1023.4, true, "1023 B"},
+		{1023.6, true, "1.0 KiB"},
+		{1024, true, "1.0 KiB"},
+		{1536, true, "1.5 KiB"},
+		{1 << 20, true, "1.0 MiB"},
+		{3 << 40, true, "3.0 TiB"},
+	}
+
+	for i, testCase := range testCases {
+		got := formatBytes(testCase.n, testCase.binary)
+		if got != testCase.expected {
+			t.Errorf(
+				"[%d] formatBytes(%v, %v)\n\n  got %#v\n  want %#v",
+				i,
+				testCase.n,
+				testCase.binary,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+}
+
+func TestBytesToken(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(1<<30, 10))
+	b.progress = 1400000
+
+	if got := (bytesToken{}).print(b); got != "1.4 MB" {
+		t.Errorf("bytesToken.print\n\n  got %#v\n  want %#v", got, "1.4 MB")
+	}
+
+	b = newTestBar(newFakeClock(), WithDimensions(1<<30, 10), WithBinaryUnits())
+	b.progress = 1400000
+
+	if got := (bytesToken{}).print(b); got != "1.3 MiB" {
+		t.Errorf("bytesToken.print with binary units\n\n  got %#v\n  want %#v", got, "1.3 MiB")
+	}
+}
+
+func TestSpeedToken(t *testing.T) {
+	var testCases = []struct {
+		rate     float64
+		binary   bool
+		expected string
+	}{
+		{0, false, "0 B/s"},
+		{512.4, false, "512 B/s"},
+		{512.4, true, "512 B/s"},
+		{999.7, false, "1.0 kB/s"},
+		{1023.6, true, "1.0 KiB/s"},
+		{3.2e9, false, "3.2 GB/s"},
+		{12.5 * (1 << 30), true, "12.5 GiB/s"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock())
+		b.rate = testCase.rate
+		b.binaryUnits = testCase.binary
+
+		if got := (speedToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] speedToken.print\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+}
+
+func TestByteETA(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithFormat(":bytes :speed :eta"))
+	b.SetBytesTotal(1000)
+
+	// a single large item, streamed a chunk at a time
+	var testCases = []struct {
+		advance  time.Duration
+		bytes    int64
+		expected string
+	}{
+		{time.Second, 100, "100 B 100 B/s 9s"},
+		{time.Second, 500, "600 B 300 B/s 1s"},
+		{2 * time.Second, 400, "1.0 kB 250 B/s 0s"},
+	}
+
+	for i, testCase := range testCases {
+		clock.advance(testCase.advance)
+		b.AddBytes(testCase.bytes)
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%d] byte eta\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+
+	// the bar's own progress doesn't affect the estimate
+	b.Tick()
+	if got, want := b.Render(), "1.0 kB 250 B/s 0s"; got != want {
+		t.Errorf("byte eta after tick\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	b.Reset()
+	clock.advance(time.Second)
+	b.SetBytes(250)
+	if got, want := b.Render(), "250 B 250 B/s 3s"; got != want {
+		t.Errorf("byte eta after reset\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestBytesWithoutTotal(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithFormat(":bytes :eta"))
+
+	// without a byte total, the estimate still comes from the items
+	for i := 0; i < 5; i++ {
+		clock.advance(time.Second)
+		b.Tick()
+		b.AddBytes(1000)
+	}
+
+	// (the item rate trails by an update, so it's 4 items over 5s)
+	if got, want := b.Render(), "5.0 kB 7s"; got != want {
+		t.Errorf("eta without byte total\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestByteRateOptions(t *testing.T) {
+	// the byte rate is measured the same way as the bar's rate
+	var testCases = []struct {
+		name     string
+		opts     []Option
+		expected string
+	}{
+		{"since start", nil, "400 B/s"},
+		{"smoothed", []Option{WithRateSmoothing(0.5)}, "614 B/s"},
+		{"windowed", []Option{WithRateWindow(2 * time.Second)}, "550 B/s"},
+	}
+
+	for _, testCase := range testCases {
+		clock := newFakeClock()
+		b := newTestBar(clock, append([]Option{WithFormat(":speed")}, testCase.opts...)...)
+		b.SetBytesTotal(10000)
+
+		// a burst after two slow seconds
+		for _, n := range []int64{100, 100, 1000} {
+			clock.advance(time.Second)
+			b.AddBytes(n)
+		}
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%s] byte rate\n\n  got %#v\n  want %#v", testCase.name, got, testCase.expected)
+		}
+	}
+}
+
+func TestByteETADone(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithDimensions(10, 2), WithFormat(":eta"), WithETADone("done"))
+	b.SetBytesTotal(1000)
+
+	// the estimate comes from the bytes, so the items reaching their total
+	// doesn't finish it
+	clock.advance(time.Second)
+	b.AddBytes(500)
+	b.Tick()
+	b.Tick()
+	if got, want := b.Render(), "1s"; got != want {
+		t.Errorf("eta with bytes remaining\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	b.AddBytes(500)
+	if got, want := b.Render(), "done"; got != want {
+		t.Errorf("eta with no bytes remaining\n\n  got %#v\n  want %#v", got, want)
+	}
+}
@@ -0,0 +1,215 @@
+package bar
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Color is an ANSI SGR escape sequence used to tint part of the bar's
+// output; any sequence may be used, such as those provided by color
+// libraries, in addition to the constants below
+type Color string
+
+// Standard ANSI foreground colors
+const (
+	NoColor Color = ""
+	Black   Color = "\x1b[30m"
+	Red     Color = "\x1b[31m"
+	Green   Color = "\x1b[32m"
+	Yellow  Color = "\x1b[33m"
+	Blue    Color = "\x1b[34m"
+	Magenta Color = "\x1b[35m"
+	Cyan    Color = "\x1b[36m"
+	White   Color = "\x1b[37m"
+	Gray    Color = "\x1b[90m"
+)
+
+// resetColor is the ANSI sequence that clears any active color
+const resetColor = "\x1b[0m"
+
+// colorNames are the names of the standard colors, as given to the color
+// option of a verb such as `:percent(color=green)`
+var colorNames = map[string]Color{
+	"black":   Black,
+	"red":     Red,
+	"green":   Green,
+	"yellow":  Yellow,
+	"blue":    Blue,
+	"magenta": Magenta,
+	"cyan":    Cyan,
+	"white":   White,
+	"gray":    Gray,
+	"grey":    Gray,
+}
+
+// RGB is a 24-bit color, for terminals that support truecolor escapes
+type RGB struct {
+	R, G, B uint8
+}
+
+// Color returns the escape sequence that sets the foreground to c
+func (c RGB) Color() Color {
+	return Color(fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B))
+}
+
+// gradientColor returns the color of cell i of n along a gradient passing
+// evenly through each of stops
+func gradientColor(stops []RGB, i, n int) Color {
+	if len(stops) == 1 || n <= 1 {
+		return stops[0].Color()
+	}
+
+	pos := float64(i) / float64(n-1) * float64(len(stops)-1)
+	k := int(pos)
+	if k >= len(stops)-1 {
+		return stops[len(stops)-1].Color()
+	}
+
+	from, to, frac := stops[k], stops[k+1], pos-float64(k)
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*frac))
+	}
+
+	return RGB{lerp(from.R, to.R), lerp(from.G, to.G), lerp(from.B, to.B)}.Color()
+}
+
+// ColorThreshold sets the color of the completed portion of the bar once
+// its progress reaches Percent (from 0 to 100)
+type ColorThreshold struct {
+	Percent float64
+	Color   Color
+}
+
+// sortedThresholds returns a copy of thresholds in ascending order
+func sortedThresholds(thresholds []ColorThreshold) []ColorThreshold {
+	sorted := append([]ColorThreshold(nil), thresholds...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Percent < sorted[j].Percent
+	})
+
+	return sorted
+}
+
+// colorEnabled decides whether colors should be emitted for an output,
+// following the NO_COLOR convention (https://no-color.org) unless colors are
+// explicitly forced
+func colorEnabled(tty, force bool) bool {
+	if force {
+		return true
+	}
+
+	return tty && os.Getenv("NO_COLOR") == ""
+}
+
+// painter writes strings of various colors to a builder, emitting escape
+// sequences only when the color changes (and not at all if it is disabled)
+type painter struct {
+	sb      *strings.Builder
+	enabled bool
+	current Color
+
+	// color, if set, replaces every color written by the painter
+	color Color
+
+	// overlay replaces the cells written at each of its non-empty indices
+	// (counting each call to write as one cell) with uncolored text
+	overlay []string
+	cell    int
+}
+
+// write writes s to the builder in color c
+func (p *painter) write(c Color, s string) {
+	if s == "" {
+		return
+	}
+
+	if p.overlay != nil {
+		if p.cell < len(p.overlay) && p.overlay[p.cell] != "" {
+			c, s = NoColor, p.overlay[p.cell]
+		}
+
+		p.cell++
+	}
+
+	p.paint(c, s)
+}
+
+// writeWide writes s, a character two columns wide, in color c, counting it
+// as two cells of the overlay; if the overlay replaces either of them, its
+// text is written in place of both, with a space for the one it doesn't
+func (p *painter) writeWide(c Color, s string) {
+	if p.overlay != nil {
+		first, second := p.overlayCell(p.cell), p.overlayCell(p.cell+1)
+		p.cell += 2
+
+		if first != "" || second != "" {
+			for _, text := range []string{first, second} {
+				if text == "" {
+					text = " "
+				}
+
+				p.paint(NoColor, text)
+			}
+
+			return
+		}
+	}
+
+	p.paint(c, s)
+}
+
+// overlayCell returns the text that replaces the given cell, or "" if the
+// overlay doesn't replace it
+func (p *painter) overlayCell(cell int) string {
+	if cell < len(p.overlay) {
+		return p.overlay[cell]
+	}
+
+	return ""
+}
+
+// writeText writes s to the builder uncolored, like the text of an overlay,
+// but without counting it as a cell of the overlay (such as a bar's caps)
+func (p *painter) writeText(s string) {
+	if s != "" {
+		p.paint(NoColor, s)
+	}
+}
+
+// paint writes s to the builder in color c, or in the painter's own color
+// if it has one
+func (p *painter) paint(c Color, s string) {
+	if p.color != NoColor {
+		c = p.color
+	}
+
+	if p.enabled && c != p.current {
+		if p.current != NoColor {
+			p.sb.WriteString(resetColor)
+		}
+
+		p.sb.WriteString(string(c))
+		p.current = c
+	}
+
+	p.sb.WriteString(s)
+}
+
+// repeat writes n copies of s to the builder in color c
+func (p *painter) repeat(c Color, s string, n int) {
+	for i := 0; i < n; i++ {
+		p.write(c, s)
+	}
+}
+
+// reset clears the active color, if any, so that text written afterwards
+// isn't tinted
+func (p *painter) reset() {
+	if p.current != NoColor {
+		p.sb.WriteString(resetColor)
+		p.current = NoColor
+	}
+}
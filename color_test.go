@@ -0,0 +1,348 @@
+package bar
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestColors(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", ">", " ", "]"), WithColors(Green, Gray))
+	b.colorize = true
+
+	var testCases = []struct {
+		progress int
+		expected string
+	}{
+		{5, "[" + string(Green) + "=>" + resetColor + string(Gray) + "  " + resetColor + "]"},
+		{0, "[" + string(Green) + ">" + resetColor + string(Gray) + "   " + resetColor + "]"},
+		{10, "[" + string(Green) + "===>" + resetColor + "]"},
+	}
+
+	for i, testCase := range testCases {
+		b.progress = testCase.progress
+
+		if got := (barToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] colored barToken.print\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+}
+
+func TestColorsOnlyCompleteSegment(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", ">", " ", "]"), WithColors(Green, NoColor))
+	b.colorize = true
+	b.progress = 5
+
+	expected := "[" + string(Green) + "=>" + resetColor + "  ]"
+	if got := (barToken{}).print(b); got != expected {
+		t.Errorf("colored barToken.print\n\n  got %#v\n  want %#v", got, expected)
+	}
+}
+
+func TestColorsSuppressedWithoutTerminal(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(
+		WithDimensions(10, 4),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithColors(Green, Gray),
+		WithWriter(&buf),
+	)
+	b.progress = 5
+
+	if got, want := (barToken{}).print(b), "[=>  ]"; got != want {
+		t.Errorf("barToken.print to a buffer\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+// setNoColor sets or unsets (if value is empty) NO_COLOR, returning a
+// function that restores its original value
+func setNoColor(value string) func() {
+	original, ok := os.LookupEnv("NO_COLOR")
+
+	if value == "" {
+		os.Unsetenv("NO_COLOR")
+	} else {
+		os.Setenv("NO_COLOR", value)
+	}
+
+	return func() {
+		if ok {
+			os.Setenv("NO_COLOR", original)
+		} else {
+			os.Unsetenv("NO_COLOR")
+		}
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	var testCases = []struct {
+		noColor    string
+		tty, force bool
+		expected   bool
+	}{
+		{"", true, false, true},
+		{"", false, false, false},
+		{"1", true, false, false},
+		{"1", false, false, false},
+		{"", false, true, true},
+		{"1", true, true, true},
+		{"1", false, true, true},
+	}
+
+	for i, testCase := range testCases {
+		restore := setNoColor(testCase.noColor)
+		got := colorEnabled(testCase.tty, testCase.force)
+		restore()
+
+		if got != testCase.expected {
+			t.Errorf(
+				"[%d] colorEnabled(%v, %v) with NO_COLOR=%#v\n\n  got %v\n  want %v",
+				i,
+				testCase.tty,
+				testCase.force,
+				testCase.noColor,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+}
+
+func TestForceColor(t *testing.T) {
+	for _, noColor := range []string{"", "1"} {
+		restore := setNoColor(noColor)
+		b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", ">", " ", "]"), WithColors(Green, NoColor), WithForceColor())
+		b.progress = 5
+		restore()
+
+		expected := "[" + string(Green) + "=>" + resetColor + "  ]"
+		if got := (barToken{}).print(b); got != expected {
+			t.Errorf("forced colors with NO_COLOR=%#v\n\n  got %#v\n  want %#v", noColor, got, expected)
+		}
+	}
+
+	restore := setNoColor("1")
+	defer restore()
+
+	var buf bytes.Buffer
+	b := NewWithOpts(WithDimensions(10, 4), WithColors(Green, Gray), WithWriter(&buf))
+	b.progress = 5
+
+	if got := (barToken{}).print(b); strings.Contains(got, "\x1b[") {
+		t.Errorf("barToken.print with NO_COLOR set contains escape sequences: %#v", got)
+	}
+}
+
+func TestColorThresholds(t *testing.T) {
+	b := newTestBar(
+		newFakeClock(),
+		WithDimensions(100, 4),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithColors(Blue, NoColor),
+		WithColorThresholds(
+			ColorThreshold{70, Green},
+			ColorThreshold{0, Red},
+			ColorThreshold{30, Yellow},
+		),
+	)
+	b.colorize = true
+
+	var testCases = []struct {
+		progress int
+		expected Color
+	}{
+		{0, Red},
+		{29, Red},
+		{30, Yellow},
+		{57, Yellow},
+		{69, Yellow},
+		{70, Green},
+		{100, Green},
+	}
+
+	for i, testCase := range testCases {
+		b.progress = testCase.progress
+
+		if got := b.fillColor(); got != testCase.expected {
+			t.Errorf("[%d] fillColor at %d%%\n\n  got %#v\n  want %#v", i, testCase.progress, got, testCase.expected)
+		}
+
+		if got := (barToken{}).print(b); !strings.HasPrefix(got, "["+string(testCase.expected)) {
+			t.Errorf("[%d] barToken.print at %d%% isn't colored %#v: %#v", i, testCase.progress, testCase.expected, got)
+		}
+	}
+}
+
+func TestColorThresholdsBelowLowest(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(100, 4), WithColors(Blue, NoColor), WithColorThresholds(ColorThreshold{50, Green}))
+
+	b.progress = 49
+	if got := b.fillColor(); got != Blue {
+		t.Errorf("fillColor below the lowest threshold\n\n  got %#v\n  want %#v", got, Blue)
+	}
+
+	b.progress = 50
+	if got := b.fillColor(); got != Green {
+		t.Errorf("fillColor at the lowest threshold\n\n  got %#v\n  want %#v", got, Green)
+	}
+}
+
+func TestColoredPercent(t *testing.T) {
+	b := newTestBar(
+		newFakeClock(),
+		WithDimensions(100, 4),
+		WithColors(Red, NoColor),
+		WithColorThresholds(ColorThreshold{40, Yellow}, ColorThreshold{80, Green}),
+		WithColoredPercent(),
+	)
+	b.colorize = true
+
+	var testCases = []struct {
+		progress int
+		expected string
+	}{
+		{10, string(Red) + "10.0%" + resetColor},
+		{40, string(Yellow) + "40.0%" + resetColor},
+		{95, string(Green) + "95.0%" + resetColor},
+	}
+
+	for i, testCase := range testCases {
+		b.progress = testCase.progress
+
+		if got := (percentToken{precision: 1}).print(b); got != testCase.expected {
+			t.Errorf("[%d] colored percentToken.print\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+
+	b.colorize = false
+	if got, want := (percentToken{precision: 1}).print(b), "95.0%"; got != want {
+		t.Errorf("colored percentToken.print without colors\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestColoredTokens(t *testing.T) {
+	b := newTestBar(
+		newFakeClock(),
+		WithDimensions(10, 4),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithFormat(":bar(color=cyan) :percent(0,color=green) :rate(0,/s,color=yellow) :name(color=red)"),
+		WithContext(Context{Ctx("name", "job")}),
+	)
+	b.progress = 5
+
+	colored := func(c Color, s string) string {
+		return string(c) + s + resetColor
+	}
+
+	b.colorize = true
+	expected := colored(Cyan, "[=>  ]") + " " + colored(Green, "50%") + " " + colored(Yellow, "0/s") + " " + colored(Red, "job")
+	if got := b.Render(); got != expected {
+		t.Errorf("colored tokens\n\n  got %#v\n  want %#v", got, expected)
+	}
+
+	// without a terminal (or with NO_COLOR set), tokens aren't colored
+	b.colorize = false
+	if got, want := b.Render(), "[=>  ] 50% 0/s job"; got != want {
+		t.Errorf("colored tokens without colors\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestColoredTokensReplaceOwnColors(t *testing.T) {
+	colored := func(c Color, s string) string {
+		return string(c) + s + resetColor
+	}
+
+	var testCases = []struct {
+		format   string
+		opts     []Option
+		expected string
+	}{
+		{":bar(color=cyan)", []Option{WithColors(Red, Green)}, colored(Cyan, "[=>  ]")},
+		{":bar(color=cyan)", []Option{WithGradientColors(RGB{0, 0, 255}, RGB{0, 255, 0})}, colored(Cyan, "[=>  ]")},
+		{":bar(color=cyan)", []Option{WithColors(Red, Green), WithSmoothFill()}, colored(Cyan, "[██  ]")},
+		{":bar(color=cyan) :count", []Option{WithColors(Red, Green)}, colored(Cyan, "[=>  ]") + " 5/10"},
+		{":percent(0,color=green)", []Option{WithColors(Red, NoColor), WithColoredPercent()}, colored(Green, "50%")},
+		{":percent(0) :bar", []Option{WithColors(Red, NoColor), WithColoredPercent()}, colored(Red, "50%") + " [" + colored(Red, "=>") + "  ]"},
+	}
+
+	for i, testCase := range testCases {
+		opts := append([]Option{
+			WithDimensions(10, 4),
+			WithDisplay("[", "=", ">", " ", "]"),
+			WithFormat(testCase.format),
+		}, testCase.opts...)
+		b := newTestBar(newFakeClock(), opts...)
+		b.colorize = true
+		b.progress = 5
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%d] %#v with its own colors\n\n  got %#v\n  want %#v", i, testCase.format, got, testCase.expected)
+		}
+	}
+}
+
+func TestColoredTokensFitWidth(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", ">", " ", "]"), WithFormat(":bar(color=green) :count"), WithFitWidth())
+	b.colorize = true
+	b.progress = 5
+	b.termWidth = func() (int, bool) {
+		return 16, true
+	}
+
+	if got := b.Render(); displayWidth(got) != 16 {
+		t.Errorf("colored bar fit to 16 columns is %d columns wide: %#v", displayWidth(got), got)
+	}
+}
+
+func TestGradientColors(t *testing.T) {
+	blue, green := RGB{0, 0, 255}, RGB{0, 255, 0}
+	cells := func(colors ...Color) string {
+		var sb strings.Builder
+		for i, c := range colors {
+			if i > 0 {
+				sb.WriteString(resetColor)
+			}
+			sb.WriteString(string(c) + "=")
+		}
+		return sb.String() + resetColor
+	}
+
+	var testCases = []struct {
+		stops    []RGB
+		progress int
+		expected string
+	}{
+		{
+			[]RGB{blue, green},
+			10,
+			"[" + cells("\x1b[38;2;0;0;255m", "\x1b[38;2;0;85;170m", "\x1b[38;2;0;170;85m", "\x1b[38;2;0;255;0m") + "]",
+		},
+		{
+			[]RGB{blue, RGB{255, 0, 0}, green},
+			10,
+			"[" + cells("\x1b[38;2;0;0;255m", "\x1b[38;2;170;0;85m", "\x1b[38;2;170;85;0m", "\x1b[38;2;0;255;0m") + "]",
+		},
+		{[]RGB{green}, 10, "[\x1b[38;2;0;255;0m====" + resetColor + "]"},
+		{[]RGB{blue, green}, 0, "[" + cells("\x1b[38;2;0;0;255m") + "   ]"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", "=", " ", "]"), WithGradientColors(testCase.stops...))
+		b.colorize = true
+		b.progress = testCase.progress
+
+		if got := (barToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] barToken.print with a gradient\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+
+	// without a terminal, no colors are shown
+	b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithDisplay("[", "=", "=", " ", "]"), WithGradientColors(blue, green))
+	b.progress = 10
+
+	if got, want := (barToken{}).print(b), "[====]"; got != want {
+		t.Errorf("barToken.print with a gradient without a terminal\n\n  got %#v\n  want %#v", got, want)
+	}
+}
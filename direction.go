@@ -0,0 +1,45 @@
+package bar
+
+// Direction determines which way the bar fills as progress is made
+type Direction int
+
+// Directions in which the bar can fill
+const (
+	// LeftToRight fills the bar from its left edge (the default)
+	LeftToRight Direction = iota
+	// RightToLeft fills the bar from its right edge
+	RightToLeft
+)
+
+// mirroredHeads maps head characters to their counterparts pointing the
+// other way
+var mirroredHeads = map[string]string{
+	">":  "<",
+	"<":  ">",
+	")":  "(",
+	"(":  ")",
+	"]":  "[",
+	"[":  "]",
+	"}":  "{",
+	"{":  "}",
+	"/":  "\\",
+	"\\": "/",
+	"»":  "«",
+	"«":  "»",
+	"▶":  "◀",
+	"◀":  "▶",
+	"▷":  "◁",
+	"◁":  "▷",
+	"→":  "←",
+	"←":  "→",
+}
+
+// mirrorHead returns the head character pointing the other way, or head
+// itself if it's symmetrical (or has no known counterpart)
+func mirrorHead(head string) string {
+	if mirrored, ok := mirroredHeads[head]; ok {
+		return mirrored
+	}
+
+	return head
+}
@@ -0,0 +1,61 @@
+package bar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// formatDuration formats d according to layout, in which `hh`, `mm` and
+// `ss` are replaced by zero-padded hours, minutes and seconds and anything
+// else is printed as is. The largest unit in the layout doesn't roll over,
+// so 1h3m is printed as `63:00` by the layout `mm:ss`.
+func formatDuration(d time.Duration, layout string) string {
+	var sb strings.Builder
+
+	if d < 0 {
+		sb.WriteString("-")
+		d = -d
+	}
+
+	seconds := int64(d / time.Second)
+	hours := strings.Contains(layout, "hh")
+	minutes := strings.Contains(layout, "mm")
+
+	for i := 0; i < len(layout); i++ {
+		if i+1 < len(layout) {
+			var n int64
+			switch layout[i : i+2] {
+			case "hh":
+				n = seconds / 3600
+			case "mm":
+				n = seconds / 60
+				if hours {
+					n %= 60
+				}
+			case "ss":
+				n = seconds
+				if hours || minutes {
+					n %= 60
+				}
+			default:
+				sb.WriteByte(layout[i])
+				continue
+			}
+
+			fmt.Fprintf(&sb, "%02d", n)
+			i++
+			continue
+		}
+
+		sb.WriteByte(layout[i])
+	}
+
+	return sb.String()
+}
+
+// isDurationLayout reports whether layout contains at least one of the
+// placeholders understood by formatDuration
+func isDurationLayout(layout string) bool {
+	return strings.Contains(layout, "hh") || strings.Contains(layout, "mm") || strings.Contains(layout, "ss")
+}
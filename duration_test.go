@@ -0,0 +1,62 @@
+package bar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	var testCases = []struct {
+		d        time.Duration
+		layout   string
+		expected string
+	}{
+		{0, "mm:ss", "00:00"},
+		{63 * time.Second, "mm:ss", "01:03"},
+		{63*time.Second + 400*time.Millisecond, "mm:ss", "01:03"},
+		{time.Hour + 3*time.Minute, "mm:ss", "63:00"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "hh:mm:ss", "01:02:03"},
+		{26 * time.Hour, "hh:mm:ss", "26:00:00"},
+		{26*time.Hour + 30*time.Minute, "hh:mm", "26:30"},
+		{90 * time.Second, "ss", "90"},
+		{90 * time.Second, "ss seconds", "90 seconds"},
+		{5 * time.Second, "mmmss", "00m05"},
+		{-63 * time.Second, "mm:ss", "-01:03"},
+	}
+
+	for i, testCase := range testCases {
+		got := formatDuration(testCase.d, testCase.layout)
+		if got != testCase.expected {
+			t.Errorf(
+				"[%d] formatDuration(%v, %#v)\n\n  got %#v\n  want %#v",
+				i,
+				testCase.d,
+				testCase.layout,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+}
+
+func TestEtaLayout(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock)
+	b.rate = 1
+	b.eta = time.Hour + 2*time.Minute + 3*time.Second
+
+	var testCases = []struct {
+		tkn      etaToken
+		expected string
+	}{
+		{etaToken{}, "1h2m3s"},
+		{etaToken{layout: "mm:ss"}, "62:03"},
+		{etaToken{layout: "hh:mm:ss"}, "01:02:03"},
+	}
+
+	for i, testCase := range testCases {
+		if got := testCase.tkn.print(b); got != testCase.expected {
+			t.Errorf("[%d] etaToken.print\n\n  got %#v\n  want %#v", i, got, testCase.expected)
+		}
+	}
+}
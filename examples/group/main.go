@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/superhawk610/bar"
+)
+
+func main() {
+	g := bar.NewGroup(os.Stdout)
+
+	var wg sync.WaitGroup
+	for i, n := range []int{20, 35, 50} {
+		b := bar.NewWithOpts(
+			bar.WithDimensions(n, 30),
+			bar.WithFormat(" :name :bar :percent "),
+			bar.WithContext(bar.Context{
+				bar.Ctx("name", string(rune('a'+i))),
+			}),
+		)
+		g.Add(b)
+
+		wg.Add(1)
+		go func(b *bar.Bar, n int) {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				b.Tick()
+				time.Sleep(200 * time.Millisecond)
+			}
+
+			b.Done()
+		}(b, n)
+	}
+
+	wg.Wait()
+	g.Stop()
+}
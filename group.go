@@ -0,0 +1,136 @@
+package bar
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Group displays several bars at once, each on its own line, redrawing
+// all of them in place whenever any one of them is updated
+type Group struct {
+	mu      sync.Mutex
+	out     io.Writer
+	bars    []*Bar
+	lines   []string
+	drawn   int
+	stopped bool
+}
+
+// NewGroup creates a new, empty group of bars that renders to w
+func NewGroup(w io.Writer) *Group {
+	return &Group{out: w}
+}
+
+// Add appends b to the group, displaying it below any bars that were
+// already added. From then on, b is drawn by the group instead of to its
+// own output.
+func (g *Group) Add(b *Bar) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.stopped {
+		return
+	}
+
+	b.group = g
+	g.bars = append(g.bars, b)
+	g.lines = append(g.lines, b.render())
+	g.draw()
+}
+
+// Render redraws all of the group's bars
+func (g *Group) Render() {
+	g.mu.Lock()
+	bars := append([]*Bar(nil), g.bars...)
+	g.mu.Unlock()
+
+	// each bar is rendered without holding the group's lock, since bars
+	// acquire it while they hold their own lock when they're updated
+	lines := make([]string, len(bars))
+	for i, b := range bars {
+		lines[i] = b.Render()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.stopped {
+		return
+	}
+
+	copy(g.lines, lines)
+	g.draw()
+}
+
+// Stop draws the group's bars one last time and stops redrawing them, with
+// the cursor left on the line below the last bar. Any updates to the bars
+// after the group is stopped aren't displayed.
+func (g *Group) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.stopped {
+		return
+	}
+
+	g.draw()
+	g.stopped = true
+}
+
+// update records line as the latest output of b and redraws the group
+func (g *Group) update(b *Bar, line string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.stopped {
+		return
+	}
+
+	for i, bar := range g.bars {
+		if bar == b {
+			g.lines[i] = line
+		}
+	}
+
+	g.draw()
+}
+
+// interrupt prints s above all of the group's bars
+func (g *Group) interrupt(s string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.stopped {
+		return
+	}
+
+	g.moveToTop()
+	fmt.Fprintf(g.out, "%s%s\n", clearLine, s)
+	g.drawn = 0
+	g.draw()
+}
+
+// draw moves the cursor back to the group's first line and redraws each
+// bar on its own line, leaving the cursor below the last one; the caller
+// must hold g.mu
+func (g *Group) draw() {
+	g.moveToTop()
+
+	for _, line := range g.lines {
+		fmt.Fprintf(g.out, "%s%s\n", clearLine, line)
+	}
+
+	g.drawn = len(g.lines)
+}
+
+// moveToTop moves the cursor up to the first line drawn by the previous
+// call to draw; the caller must hold g.mu
+func (g *Group) moveToTop() {
+	if g.drawn > 0 {
+		fmt.Fprintf(g.out, "\x1b[%dA", g.drawn)
+	}
+}
@@ -0,0 +1,102 @@
+package bar
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	var buf bytes.Buffer
+
+	g := NewGroup(&buf)
+	first := newTestBar(newFakeClock(), WithDimensions(2, 10), WithFormat("a :count"))
+	second := newTestBar(newFakeClock(), WithDimensions(3, 10), WithFormat("b :count"))
+
+	g.Add(first)
+	g.Add(second)
+	first.Tick()
+	second.Tick()
+	first.Tick()
+	first.Done()
+	second.Interrupt("hello")
+	second.Tick()
+	g.Stop()
+	second.Tick()
+
+	line := func(s string) string {
+		return clearLine + s + "\n"
+	}
+	up := func(n int) string {
+		return fmt.Sprintf("\x1b[%dA", n)
+	}
+
+	expected := line("a 0/2") +
+		up(1) + line("a 0/2") + line("b 0/3") +
+		up(2) + line("a 1/2") + line("b 0/3") +
+		up(2) + line("a 1/2") + line("b 1/3") +
+		up(2) + line("a 2/2") + line("b 1/3") +
+		up(2) + line("a 2/2") + line("b 1/3") +
+		up(2) + line("hello") + line("a 2/2") + line("b 1/3") +
+		up(2) + line("a 2/2") + line("b 2/3") +
+		up(2) + line("a 2/2") + line("b 2/3")
+
+	if got := buf.String(); got != expected {
+		t.Errorf("group output\n\n  got %#v\n  want %#v", got, expected)
+	}
+}
+
+func TestGroupRender(t *testing.T) {
+	var buf bytes.Buffer
+
+	g := NewGroup(&buf)
+	b := newTestBar(newFakeClock(), WithFormat(":spinner"))
+	g.Add(b)
+	buf.Reset()
+
+	g.Render()
+
+	if got, want := buf.String(), "\x1b[1A"+clearLine+"/\n"; got != want {
+		t.Errorf("group output after Render\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestGroupConcurrentUpdates(t *testing.T) {
+	var buf bytes.Buffer
+
+	g := NewGroup(&buf)
+	bars := make([]*Bar, 5)
+	for i := range bars {
+		bars[i] = newTestBar(newFakeClock(), WithDimensions(100, 10))
+		g.Add(bars[i])
+	}
+
+	var wg sync.WaitGroup
+	for _, b := range bars {
+		wg.Add(1)
+		go func(b *Bar) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				b.Add(1)
+			}
+		}(b)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			g.Render()
+		}
+	}()
+
+	wg.Wait()
+	g.Stop()
+
+	for i, b := range bars {
+		if b.progress != 100 {
+			t.Errorf("[%d] progress after concurrent updates\n\n  got %d\n  want %d", i, b.progress, 100)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package bar
+
+import "encoding/json"
+
+// Mode determines how the bar is written to its output
+type Mode int
+
+// Modes in which the bar can be written
+const (
+	// ModeBar draws the bar in place on a single line (the default)
+	ModeBar Mode = iota
+	// ModeJSON writes a JSON object describing the bar's progress on a new
+	// line each time it would otherwise be drawn, for logs and other
+	// non-interactive environments
+	ModeJSON
+)
+
+// jsonFrame is the object written for each update in ModeJSON. Fields that
+// aren't known yet (such as the ETA before a rate has been established)
+// are null.
+type jsonFrame struct {
+	Progress int               `json:"progress"`
+	Total    int               `json:"total"`
+	Percent  *float64          `json:"percent"`
+	Rate     float64           `json:"rate"`
+	ETA      *float64          `json:"eta"`
+	Context  map[string]string `json:"context,omitempty"`
+}
+
+// writeJSON writes the bar's current state as a line of JSON; the caller
+// must hold b.mu
+func (b *Bar) writeJSON() {
+	frame := jsonFrame{
+		Progress: b.progress,
+		Total:    b.total,
+	}
+
+	if !b.indeterminate() {
+		percent := b.prog() * 100
+		frame.Percent = &percent
+	}
+
+	if b.hasRate() {
+		frame.Rate = b.rate
+	}
+
+	if eta, ok := b.estimate(); ok {
+		seconds := eta.Seconds()
+		frame.ETA = &seconds
+	}
+
+	if len(b.context) > 0 {
+		frame.Context = make(map[string]string, len(b.context))
+		for _, def := range b.context {
+			frame.Context[def.verb] = def.valueFor(b)
+		}
+	}
+
+	line, err := json.Marshal(frame)
+	if err != nil {
+		b.warnf("bar: unable to encode progress as JSON: %v\n", err)
+		return
+	}
+
+	b.printLine(string(line))
+}
+
+// jsonMessage is the object written for an interruption in ModeJSON, so that
+// every line of the output is still an object
+type jsonMessage struct {
+	Message string `json:"message"`
+}
+
+// writeJSONMessage writes s as a line of JSON; the caller must hold b.mu
+func (b *Bar) writeJSONMessage(s string) {
+	line, err := json.Marshal(jsonMessage{s})
+	if err != nil {
+		b.warnf("bar: unable to encode message as JSON: %v\n", err)
+		return
+	}
+
+	b.printLine(string(line))
+}
@@ -0,0 +1,67 @@
+package bar
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModeJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	clock := newFakeClock()
+	b := NewWithOpts(
+		WithDimensions(4, 10),
+		WithWriter(&buf),
+		WithMode(ModeJSON),
+		WithContext(Context{Ctx("file", "a.txt")}),
+	)
+	b.now = clock.now
+	b.startedAt = clock.now()
+
+	clock.advance(time.Second)
+	b.Tick()
+	clock.advance(time.Second)
+	b.TickAndUpdate(Context{Ctx("file", "b.txt")})
+	b.Interrupt("hello")
+	b.Finish()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+	expected := []map[string]interface{}{
+		{"progress": 1.0, "total": 4.0, "percent": 25.0, "rate": 0.0, "eta": nil, "context": map[string]interface{}{"file": "a.txt"}},
+		{"progress": 2.0, "total": 4.0, "percent": 50.0, "rate": 0.5, "eta": 6.0, "context": map[string]interface{}{"file": "b.txt"}},
+		{"message": "hello"},
+		{"progress": 4.0, "total": 4.0, "percent": 100.0, "rate": 0.5, "eta": 0.0, "context": map[string]interface{}{"file": "b.txt"}},
+	}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("lines written in JSON mode\n\n  got %#v\n  want %d lines", lines, len(expected))
+	}
+
+	for i, line := range lines {
+		var got map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Errorf("[%d] line %#v isn't valid JSON: %v", i, line, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, expected[i]) {
+			t.Errorf("[%d] JSON frame\n\n  got %#v\n  want %#v", i, got, expected[i])
+		}
+	}
+}
+
+func TestModeJSONCustomOutput(t *testing.T) {
+	out := &recordingOutput{}
+	b := NewWithOpts(WithDimensions(2, 10), WithOutput(out), WithMode(ModeJSON))
+	b.Tick()
+	b.Interrupt("hello")
+
+	if len(out.calls) != 2 || !strings.HasPrefix(out.calls[0], `{"progress":1,`) || out.calls[1] != `{"message":"hello"}`+"\n" {
+		t.Errorf("calls to a custom output in JSON mode\n\n  got %#v", out.calls)
+	}
+}
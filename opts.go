@@ -6,6 +6,8 @@ package bar
 
 import (
 	"fmt"
+	"io"
+	"strings"
 	"time"
 )
 
@@ -14,81 +16,249 @@ type barOpts struct {
 	start, end                 string
 	complete, head, incomplete string
 	formatString               string
+	prefix                     string
+	suffix                     string
 	callback                   func()
 	output                     Output
 	context                    Context
 	debug                      bool
+	binaryUnits                bool
+	spinner                    []string
+	formatOpts                 formatOpts
+	minInterval                time.Duration
+	smooth                     bool
+	completeColor              Color
+	incompleteColor            Color
+	forceColor                 bool
+	thresholds                 []ColorThreshold
+	fitWidth                   bool
+	widthPercent               int
+	maxWidth                   int
+	ellipsis                   string
+	rateSmoothing              float64
+	rateWindow                 time.Duration
+	direction                  Direction
+	countdown                  bool
+	gradient                   []RGB
+	newlineOnFinish            bool
+	mode                       Mode
+	tty                        *bool
+	onRender                   func(Snapshot)
+	onFinish                   func(Snapshot)
+	colorPercent               bool
+	percentInBar               bool
+	headFrames                 []string
+	completePattern            string
+	incompletePattern          string
+	hideFullHead               bool
+	hideZeroHead               bool
+	rounding                   Rounding
+	overshoot                  OvershootPolicy
+	etaDone                    string
+	padPercent                 bool
+	padCount                   bool
+	hideUntilStart             bool
+	timeRenders                bool
+	refreshInterval            time.Duration
+	decimalSep                 string
+	percentSpace               bool
+	roundDown                  bool
+	redrawOnResize             bool
+	noCaps                     bool
 }
 
-type augment func(*barOpts)
+// Option customizes a bar created by New, NewWithOpts or TryNewWithOpts
+type Option func(*barOpts)
 
 // NewWithFormat creates a new instance of bar.Bar with the given total
 // and format and returns a reference to it
 func NewWithFormat(t int, f string) *Bar {
-	return &Bar{
-		progress:     0,
-		total:        t,
-		width:        20,
-		start:        "(",
-		complete:     "█",
-		head:         "█",
-		incomplete:   " ",
-		end:          ")",
-		closed:       false,
-		startedAt:    time.Now(),
-		rate:         0,
-		formatString: f,
-		format:       tokenize(f, nil),
-		callback:     noop,
-		output:       initializeStdout(),
-	}
+	return New(t, WithFormat(f))
 }
 
 // NewWithOpts creates a new instance of bar.Bar with the provided options
-// and returns a reference to it
-func NewWithOpts(opts ...func(o *barOpts)) *Bar {
+// and returns a reference to it. It panics if the options are invalid; use
+// TryNewWithOpts to handle the error instead.
+func NewWithOpts(opts ...Option) *Bar {
+	b, err := TryNewWithOpts(opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return b
+}
+
+// TryNewWithOpts creates a new instance of bar.Bar with the provided options
+// and returns a reference to it, or an error if the options are invalid
+// (such as a non-positive width or a format string that can't be parsed)
+func TryNewWithOpts(opts ...Option) (*Bar, error) {
 	o := &barOpts{
-		start:        "(",
-		complete:     "█",
-		head:         "█",
-		incomplete:   " ",
-		end:          ")",
-		formatString: defaultFormat,
-		callback:     noop,
-		output:       initializeStdout(),
+		width:           20,
+		start:           "(",
+		complete:        "█",
+		head:            "█",
+		incomplete:      " ",
+		end:             ")",
+		formatString:    defaultFormat,
+		callback:        noop,
+		output:          initializeStdout(),
+		spinner:         defaultSpinner,
+		newlineOnFinish: true,
 	}
 
 	for _, aug := range opts {
 		aug(o)
 	}
 
+	if o.noCaps {
+		o.start, o.end = "", ""
+	}
+
+	// every cell of the bar needs a character, or it won't have the width
+	// it's meant to; the caps on either end may be left empty, though
+	if o.complete == "" {
+		o.complete = "█"
+	}
+
+	if o.head == "" {
+		o.head = patternCells("", o.complete)[0]
+	}
+
+	if o.incomplete == "" {
+		o.incomplete = " "
+	}
+
 	if o.width <= 0 {
-		panic(fmt.Sprintf("a bar may not have a zero or negative width (received: %d)", o.width))
+		return nil, fmt.Errorf("a bar may not have a zero or negative width (received: %d)", o.width)
 	}
 
-	return &Bar{
-		progress:     0,
-		total:        o.total,
-		width:        o.width,
-		start:        o.start,
-		complete:     o.complete,
-		head:         o.head,
-		incomplete:   o.incomplete,
-		end:          o.end,
-		closed:       false,
-		startedAt:    time.Now(),
-		rate:         0,
-		formatString: o.formatString,
-		format:       tokenize(o.formatString, o.context.customVerbs()),
-		callback:     o.callback,
-		output:       o.output,
-		context:      o.context,
-		debug:        o.debug,
+	if o.widthPercent < 0 || o.widthPercent > 100 {
+		return nil, fmt.Errorf("a width percentage must be between 0 and 100 (received: %d)", o.widthPercent)
 	}
+
+	if o.rateSmoothing < 0 || o.rateSmoothing > 1 {
+		return nil, fmt.Errorf("a rate smoothing factor must be between 0 and 1 (received: %v)", o.rateSmoothing)
+	}
+
+	if o.refreshInterval < 0 {
+		return nil, fmt.Errorf("a refresh interval may not be negative (received: %v)", o.refreshInterval)
+	}
+
+	if o.rateWindow < 0 {
+		return nil, fmt.Errorf("a rate window may not be negative (received: %v)", o.rateWindow)
+	}
+
+	if o.rateWindow > 0 && o.rateSmoothing > 0 {
+		return nil, fmt.Errorf("a rate window and rate smoothing may not be used together")
+	}
+
+	if len(o.spinner) == 0 {
+		return nil, fmt.Errorf("a spinner must have at least one frame")
+	}
+
+	for _, frame := range o.headFrames {
+		if frame == "" {
+			return nil, fmt.Errorf("a head frame may not be empty")
+		}
+	}
+
+	format, err := parseFormat(strings.NewReader(o.formatString), o.context.customVerbs(), o.formatOpts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format %q: %v", o.formatString, err)
+	}
+
+	if err := format.checkCustomVerbs(o.context); err != nil {
+		return nil, fmt.Errorf("invalid format %q: %v", o.formatString, err)
+	}
+
+	tty := isTerminal(o.output)
+	colorize := colorEnabled(tty, o.forceColor)
+
+	// an Output that can't be inspected is still redrawn in place, although
+	// it isn't colored unless asked to be
+	if !canInspect(o.output) {
+		tty = true
+	}
+
+	if o.tty != nil {
+		tty = *o.tty
+		colorize = colorEnabled(tty, o.forceColor)
+	}
+
+	return &Bar{
+		progress:        0,
+		total:           o.total,
+		width:           o.width,
+		start:           o.start,
+		complete:        o.complete,
+		head:            o.head,
+		incomplete:      o.incomplete,
+		end:             o.end,
+		closed:          false,
+		startedAt:       time.Now(),
+		now:             time.Now,
+		rate:            0,
+		formatString:    o.formatString,
+		format:          format,
+		prefix:          o.prefix,
+		suffix:          o.suffix,
+		callback:        o.callback,
+		output:          o.output,
+		context:         o.context,
+		debug:           o.debug,
+		binaryUnits:     o.binaryUnits,
+		spinner:         o.spinner,
+		formatOpts:      o.formatOpts,
+		minInterval:     o.minInterval,
+		smooth:          o.smooth,
+		completeColor:   o.completeColor,
+		incompleteColor: o.incompleteColor,
+		colorize:        colorize,
+		tty:             tty,
+		thresholds:      sortedThresholds(o.thresholds),
+		fitWidth:        o.fitWidth,
+		widthPercent:    o.widthPercent,
+		maxWidth:        o.maxWidth,
+		ellipsis:        o.ellipsis,
+		rateSmoothing:   o.rateSmoothing,
+		rateWindow:      o.rateWindow,
+		direction:       o.direction,
+		countdown:       o.countdown,
+		gradient:        o.gradient,
+		newlineOnFinish: o.newlineOnFinish,
+		mode:            o.mode,
+		onRender:        o.onRender,
+		onFinish:        o.onFinish,
+		colorPercent:    o.colorPercent,
+		percentInBar:    o.percentInBar,
+		headFrames:      o.headFrames,
+		completeCells:   patternCells(o.completePattern, o.complete),
+		incompleteCells: patternCells(o.incompletePattern, o.incomplete),
+		hideFullHead:    o.hideFullHead,
+		hideZeroHead:    o.hideZeroHead,
+		rounding:        o.rounding,
+		overshoot:       o.overshoot,
+		etaDone:         o.etaDone,
+		padPercent:      o.padPercent,
+		padCount:        o.padCount,
+		hideUntilStart:  o.hideUntilStart,
+		timeRenders:     o.timeRenders,
+		refreshInterval: o.refreshInterval,
+		decimalSep:      o.decimalSep,
+		percentSpace:    o.percentSpace,
+		roundDown:       o.roundDown,
+		redrawOnResize:  o.redrawOnResize,
+		notifyResize:    notifyResize,
+		termWidth: func() (int, bool) {
+			return outputWidth(o.output)
+		},
+	}, nil
 }
 
 // WithDisplay augments an options constructor by customizing terminal
-// output characters
+// output characters; a complete or incomplete of more than one character
+// is tiled across the bar's cells, like WithCompletePattern and
+// WithIncompletePattern
 //
 // [ xxx >    ]
 // | |   | |  |- end
@@ -96,7 +266,7 @@ func NewWithOpts(opts ...func(o *barOpts)) *Bar {
 // | |   |- head
 // | |- complete
 // |- start
-func WithDisplay(start, complete, head, incomplete, end string) augment {
+func WithDisplay(start, complete, head, incomplete, end string) Option {
 	return func(o *barOpts) {
 		o.start = start
 		o.complete = complete
@@ -106,40 +276,106 @@ func WithDisplay(start, complete, head, incomplete, end string) augment {
 	}
 }
 
+// WithoutCaps augments an options constructor by leaving out the characters
+// on either end of the bar, so that it's made up of its cells alone, even if
+// WithDisplay gives it caps
+func WithoutCaps() Option {
+	return func(o *barOpts) {
+		o.noCaps = true
+	}
+}
+
 // WithDimensions augments an options constructor by customizing the
 // bar's width and total
-func WithDimensions(total, width int) augment {
+func WithDimensions(total, width int) Option {
 	return func(o *barOpts) {
 		o.total = total
 		o.width = width
 	}
 }
 
+// WithWidth augments an options constructor by customizing the bar's
+// width, in cells
+func WithWidth(width int) Option {
+	return func(o *barOpts) {
+		o.width = width
+	}
+}
+
 // WithFormat augments an options constructor by customizing the bar's
 // output format
-func WithFormat(f string) augment {
+func WithFormat(f string) Option {
 	return func(o *barOpts) {
 		o.formatString = f
 	}
 }
 
+// WithPrefix augments an options constructor by printing s before the
+// formatted bar as is, without parsing it for verbs, so that it can contain
+// colons and other special characters without escaping them
+func WithPrefix(s string) Option {
+	return func(o *barOpts) {
+		o.prefix = s
+	}
+}
+
+// WithSuffix augments an options constructor by printing s after the
+// formatted bar as is, like WithPrefix
+func WithSuffix(s string) Option {
+	return func(o *barOpts) {
+		o.suffix = s
+	}
+}
+
 // WithCallback augments an options constructor by setting a callback
-func WithCallback(cb func()) augment {
+func WithCallback(cb func()) Option {
 	return func(o *barOpts) {
 		o.callback = cb
 	}
 }
 
+// WithOnRender augments an options constructor by setting a hook that is
+// called with a snapshot of the bar each time it's rendered (whether it's
+// being drawn or returned by Render), such as to mirror its progress
+// elsewhere. The hook is called while the bar is locked, so it must not call
+// any of the bar's methods.
+func WithOnRender(fn func(Snapshot)) Option {
+	return func(o *barOpts) {
+		o.onRender = fn
+	}
+}
+
+// WithOnFinish augments an options constructor by setting a hook that is
+// called with a snapshot of the bar once it completes, either when its
+// progress reaches its total or when Done or Finish is called, whichever
+// happens first; it isn't called again until the bar is reset. The hook is
+// called once the bar is unlocked again, so it may call the bar's methods
+// (such as Reset, to start on the next task).
+func WithOnFinish(fn func(Snapshot)) Option {
+	return func(o *barOpts) {
+		o.onFinish = fn
+	}
+}
+
 // WithOutput augments an options constructor by setting the output stream
-func WithOutput(out Output) augment {
+func WithOutput(out Output) Option {
 	return func(o *barOpts) {
 		o.output = out
 	}
 }
 
+// WithWriter augments an options constructor by rendering the bar to w;
+// it's a shortcut for WithOutput for writers that don't need any special
+// handling to clear the current line
+func WithWriter(w io.Writer) Option {
+	return func(o *barOpts) {
+		o.output = &writerOutput{w: w}
+	}
+}
+
 // WithContext augments an options constructor by setting the initial values
 // for the bar's context
-func WithContext(ctx Context) augment {
+func WithContext(ctx Context) Option {
 	return func(o *barOpts) {
 		o.context = ctx
 	}
@@ -148,8 +384,425 @@ func WithContext(ctx Context) augment {
 // WithDebug augments an options constructor by setting the internal
 // debug flag to true; this will display the list of internal tokens recognized
 // on each Tick/Update in place of the standard output
-func WithDebug() augment {
+func WithDebug() Option {
 	return func(o *barOpts) {
 		o.debug = true
 	}
 }
+
+// WithBinaryUnits augments an options constructor by displaying byte
+// quantities in base-1024 (IEC) units like `MiB` instead of the default
+// base-1000 (SI) units like `MB`
+func WithBinaryUnits() Option {
+	return func(o *barOpts) {
+		o.binaryUnits = true
+	}
+}
+
+// WithSpinner augments an options constructor by customizing the frames
+// displayed by the `:spinner` verb, one per render; each frame may be any
+// string, such as a braille character or an emoji
+func WithSpinner(frames ...string) Option {
+	return func(o *barOpts) {
+		o.spinner = frames
+	}
+}
+
+// WithCaseInsensitiveVerbs augments an options constructor by matching
+// verbs in the format string regardless of case, so that `:BAR` and `:Bar`
+// are treated the same as `:bar`
+func WithCaseInsensitiveVerbs() Option {
+	return func(o *barOpts) {
+		o.formatOpts.foldCase = true
+	}
+}
+
+// WithStrictVerbs augments an options constructor by rejecting formats that
+// contain unknown verbs (such as a typo like `:percnt`) or verbs with invalid
+// arguments, rather than printing them as literals; TryNewWithOpts returns an
+// error naming the verb
+func WithStrictVerbs() Option {
+	return func(o *barOpts) {
+		o.formatOpts.strict = true
+	}
+}
+
+// WithSeparators augments an options constructor by ending literals and
+// verbs in the format string at any of the characters in chars, as well as
+// at spaces, tabs, colons and quotes. Each separator is a literal of its
+// own, so with "|" the format `done|:bar` is read as `done`, `|` and `:bar`.
+func WithSeparators(chars string) Option {
+	return func(o *barOpts) {
+		o.formatOpts.separators = chars
+	}
+}
+
+// WithNewlineOnFinish augments an options constructor by determining
+// whether a new line is printed after the bar's final frame (when Done or
+// Finish is called), which it is by default. Disable it if you manage the
+// layout around the bar yourself; frames are always drawn over the current
+// line rather than on new lines.
+func WithNewlineOnFinish(enabled bool) Option {
+	return func(o *barOpts) {
+		o.newlineOnFinish = enabled
+	}
+}
+
+// WithTTY augments an options constructor by overriding whether the bar's
+// output is treated as a terminal, which is otherwise detected. Outputs that
+// aren't terminals are written line by line rather than drawn in place
+// (see WithMode), and don't show colors. Outputs from WithOutput can't be
+// detected, so they're drawn in place (but without colors) by default.
+func WithTTY(isTTY bool) Option {
+	return func(o *barOpts) {
+		o.tty = &isTTY
+	}
+}
+
+// WithMode augments an options constructor by changing how the bar is
+// written to its output; with ModeJSON, a JSON object describing the bar's
+// progress is written on its own line for each update instead of drawing it
+func WithMode(m Mode) Option {
+	return func(o *barOpts) {
+		o.mode = m
+	}
+}
+
+// WithMinInterval augments an options constructor by limiting how often
+// the bar is redrawn; updates that arrive less than d after the last draw
+// are still recorded, but the bar isn't redrawn until the next update after
+// d has passed (or the bar completes)
+func WithMinInterval(d time.Duration) Option {
+	return func(o *barOpts) {
+		o.minInterval = d
+	}
+}
+
+// WithRenderTiming augments an options constructor by recording how long
+// each render takes, for LastRenderDuration
+func WithRenderTiming() Option {
+	return func(o *barOpts) {
+		o.timeRenders = true
+	}
+}
+
+// WithRefreshInterval augments an options constructor by setting how often
+// the bar is redrawn in the background once Start is called (every 100ms by
+// default)
+func WithRefreshInterval(d time.Duration) Option {
+	return func(o *barOpts) {
+		o.refreshInterval = d
+	}
+}
+
+// WithRedrawOnResize augments an options constructor by redrawing the bar
+// whenever the terminal is resized (on SIGWINCH), so that a bar sized with
+// WithFitWidth or WithWidthPercent follows the new width straight away; it
+// does nothing on platforms without the signal. The signal is watched from
+// when the bar is first drawn until it's finished, so a bar that's drawn
+// must be finished with Done or Finish (or stopped by Watch) to release it.
+func WithRedrawOnResize() Option {
+	return func(o *barOpts) {
+		o.redrawOnResize = true
+	}
+}
+
+// WithRateSmoothing augments an options constructor by smoothing the bar's
+// rate (and the ETA derived from it) with an exponentially weighted moving
+// average, so that bursty updates don't make them jump around. Each update
+// is weighted by factor, between 0 and 1; smaller factors give smoother
+// but slower to react rates. The rate of bytes recorded with AddBytes is
+// smoothed the same way. By default, the rate is the average since the bar
+// was created.
+func WithRateSmoothing(factor float64) Option {
+	return func(o *barOpts) {
+		o.rateSmoothing = factor
+	}
+}
+
+// WithRateWindow augments an options constructor by measuring the bar's
+// rate (and the ETA derived from it) over the last d, rather than since the
+// bar was created, so that it reflects recent throughput for workloads
+// whose speed changes. The rate of bytes recorded with AddBytes is measured
+// over the same window. It may not be combined with WithRateSmoothing.
+func WithRateWindow(d time.Duration) Option {
+	return func(o *barOpts) {
+		o.rateWindow = d
+	}
+}
+
+// WithETADone augments an options constructor by showing s in place of
+// :eta once the bar has reached its total (or, once SetBytesTotal has been
+// called, its total of bytes), rather than the last estimate
+func WithETADone(s string) Option {
+	return func(o *barOpts) {
+		o.etaDone = s
+	}
+}
+
+// WithPercentInBar augments an options constructor by showing the bar's
+// percentage over the center of the bar itself, in place of the cells
+// beneath it, so the bar keeps its width; it isn't shown if it doesn't fit
+func WithPercentInBar() Option {
+	return func(o *barOpts) {
+		o.percentInBar = true
+	}
+}
+
+// WithSmoothFill augments an options constructor by filling the bar with
+// Unicode block characters that advance in eighths of a cell, rather than
+// whole cells; the bar's complete and head characters are not used
+func WithSmoothFill() Option {
+	return func(o *barOpts) {
+		o.smooth = true
+	}
+}
+
+// WithCompletePattern augments an options constructor by tiling the
+// completed cells of the bar with pattern, one character per cell, in place
+// of the single character set by WithDisplay
+func WithCompletePattern(pattern string) Option {
+	return func(o *barOpts) {
+		o.completePattern = pattern
+	}
+}
+
+// WithIncompletePattern augments an options constructor by tiling the
+// incomplete cells of the bar with pattern, one character per cell (such as
+// "·-" for a dotted look), in place of the single character set by
+// WithDisplay
+func WithIncompletePattern(pattern string) Option {
+	return func(o *barOpts) {
+		o.incompletePattern = pattern
+	}
+}
+
+// wideTail stands in for the second of the two cells covered by a wide
+// character (such as "あ") in the cells returned by patternCells
+const wideTail = "\x00"
+
+// patternCells splits pattern (or glyph, if there's no pattern) into the
+// glyphs of the cells it tiles, one per column: a wide character covers two
+// cells, the second of which is a wideTail, and a zero-width character
+// (such as a combining accent) joins the cell before it
+func patternCells(pattern, glyph string) []string {
+	if pattern == "" {
+		pattern = glyph
+	}
+
+	var cells []string
+	for _, r := range pattern {
+		switch w := runeWidth(r); {
+		case w == 0 && len(cells) > 0:
+			last := len(cells) - 1
+			if cells[last] == wideTail {
+				last--
+			}
+
+			cells[last] += string(r)
+		case w == 2:
+			cells = append(cells, string(r), wideTail)
+		default:
+			cells = append(cells, string(r))
+		}
+	}
+
+	return cells
+}
+
+// WithHeadFrames augments an options constructor by cycling the bar's head
+// through the given frames, advancing one frame each time the bar is drawn,
+// to suggest motion (such as ">", "»", "›"); the frames take the place of
+// the head set by WithDisplay
+func WithHeadFrames(frames ...string) Option {
+	return func(o *barOpts) {
+		o.headFrames = frames
+	}
+}
+
+// WithHiddenHeadWhenFull augments an options constructor by drawing the bar
+// without a head once it's complete, so that every cell of a full bar is a
+// complete cell
+func WithHiddenHeadWhenFull() Option {
+	return func(o *barOpts) {
+		o.hideFullHead = true
+	}
+}
+
+// WithHideUntilStart augments an options constructor by not drawing the bar
+// at all until its progress first moves above zero, so that nothing is
+// shown during a long preamble
+func WithHideUntilStart() Option {
+	return func(o *barOpts) {
+		o.hideUntilStart = true
+	}
+}
+
+// WithHiddenHeadAtZero augments an options constructor by drawing the bar
+// without a head until at least one cell is complete, so that an empty bar is
+// made up entirely of incomplete cells
+func WithHiddenHeadAtZero() Option {
+	return func(o *barOpts) {
+		o.hideZeroHead = true
+	}
+}
+
+// WithDecimalSeparator augments an options constructor by showing sep in
+// place of the decimal point in the bar's percentages, such as "," for
+// locales that write 42,5% rather than 42.5%
+func WithDecimalSeparator(sep string) Option {
+	return func(o *barOpts) {
+		o.decimalSep = sep
+	}
+}
+
+// WithSpaceBeforePercent augments an options constructor by separating the
+// bar's percentages from their percent signs with a space (42.5 %)
+func WithSpaceBeforePercent() Option {
+	return func(o *barOpts) {
+		o.percentSpace = true
+	}
+}
+
+// WithPaddedPercent augments an options constructor by padding :percent
+// with spaces to the width of 100%, so that the rest of the line doesn't
+// shift as the percentage grows
+func WithPaddedPercent() Option {
+	return func(o *barOpts) {
+		o.padPercent = true
+	}
+}
+
+// WithPaddedCount augments an options constructor by padding the progress
+// shown by :count with spaces to the width of the total, so that the rest
+// of the line doesn't shift as the progress grows
+func WithPaddedCount() Option {
+	return func(o *barOpts) {
+		o.padCount = true
+	}
+}
+
+// WithCellRounding augments an options constructor by changing how the
+// bar's fill is rounded to whole cells; with RoundingNearest or RoundingCeil,
+// a bar that's nearly complete looks nearly complete
+func WithCellRounding(r Rounding) Option {
+	return func(o *barOpts) {
+		o.rounding = r
+	}
+}
+
+// WithOvershootPolicy augments an options constructor by changing what
+// happens when the bar's progress is set past its total; with
+// OvershootError, Add and Set return an error rather than clamping it
+func WithOvershootPolicy(p OvershootPolicy) Option {
+	return func(o *barOpts) {
+		o.overshoot = p
+	}
+}
+
+// WithConsistentRounding augments an options constructor by rounding the
+// bar's percentage to its precision the same way the bar's fill is rounded to
+// whole cells (down, unless WithCellRounding is used), so that the two always
+// agree; for example, the percentage won't read 100.0% until the bar is full
+func WithConsistentRounding() Option {
+	return func(o *barOpts) {
+		o.roundDown = true
+	}
+}
+
+// WithDirection augments an options constructor by changing the direction
+// in which the bar fills; with RightToLeft, the completed cells are drawn on
+// the right and the head points left. Smooth fills are always drawn left to
+// right, since there are no block characters for the right side of a cell.
+func WithDirection(d Direction) Option {
+	return func(o *barOpts) {
+		o.direction = d
+	}
+}
+
+// WithCountdown augments an options constructor by drawing the bar full at
+// first and emptying it as progress is made, such as to show the budget or
+// time that's left; the bar is drawn without a head once it's empty
+func WithCountdown() Option {
+	return func(o *barOpts) {
+		o.countdown = true
+	}
+}
+
+// WithColors augments an options constructor by coloring the completed and
+// incomplete portions of the bar; colors are only shown when the output is
+// a terminal and the NO_COLOR environment variable isn't set
+func WithColors(complete, incomplete Color) Option {
+	return func(o *barOpts) {
+		o.completeColor = complete
+		o.incompleteColor = incomplete
+	}
+}
+
+// WithForceColor augments an options constructor by always emitting colors,
+// even if the output isn't a terminal or the NO_COLOR environment variable
+// is set
+func WithForceColor() Option {
+	return func(o *barOpts) {
+		o.forceColor = true
+	}
+}
+
+// WithColorThresholds augments an options constructor by changing the color
+// of the completed portion of the bar as its progress increases; each
+// threshold's color is used once the bar's percentage reaches it, and the
+// complete color from WithColors is used below the lowest threshold
+func WithColorThresholds(thresholds ...ColorThreshold) Option {
+	return func(o *barOpts) {
+		o.thresholds = thresholds
+	}
+}
+
+// WithGradientColors augments an options constructor by coloring each
+// completed cell of the bar along a gradient passing through colors, from
+// where the bar starts filling to its head; this takes precedence over the
+// colors from WithColors and WithColorThresholds, but isn't applied to smooth
+// fills. Like other colors, gradients are only shown on terminals.
+func WithGradientColors(colors ...RGB) Option {
+	return func(o *barOpts) {
+		o.gradient = colors
+	}
+}
+
+// WithColoredPercent augments an options constructor by coloring the
+// text of :percent the same as the completed portion of the bar, so that it
+// follows the thresholds from WithColorThresholds
+func WithColoredPercent() Option {
+	return func(o *barOpts) {
+		o.colorPercent = true
+	}
+}
+
+// WithFitWidth augments an options constructor by sizing the bar to fill
+// the terminal's width, leaving room for the rest of the format; the width
+// from WithDimensions is used if the output isn't a terminal
+func WithFitWidth() Option {
+	return func(o *barOpts) {
+		o.fitWidth = true
+	}
+}
+
+// WithWidthPercent augments an options constructor by sizing the bar to
+// percent of the terminal's columns, measured each time it's rendered so
+// that it follows resizes; the width from WithDimensions is used if the
+// output isn't a terminal
+func WithWidthPercent(percent int) Option {
+	return func(o *barOpts) {
+		o.widthPercent = percent
+	}
+}
+
+// WithMaxWidth augments an options constructor by truncating the rendered
+// line to at most cols columns so it never wraps, ending it with ellipsis
+// (which may be empty) when anything was cut off
+func WithMaxWidth(cols int, ellipsis string) Option {
+	return func(o *barOpts) {
+		o.maxWidth = cols
+		o.ellipsis = ellipsis
+	}
+}
@@ -0,0 +1,171 @@
+package bar
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTryNewWithOpts(t *testing.T) {
+	b, err := TryNewWithOpts(WithDimensions(10, 20), WithFormat(" :bar :percent "))
+	if err != nil {
+		t.Fatalf("TryNewWithOpts returned unexpected error: %v", err)
+	}
+
+	if b == nil {
+		t.Fatal("TryNewWithOpts returned a nil bar without an error")
+	}
+}
+
+func TestTryNewWithOptsRejectsInvalidWidth(t *testing.T) {
+	for i, width := range []int{0, -1, -80} {
+		for _, opt := range []Option{WithDimensions(10, width), WithWidth(width)} {
+			b, err := TryNewWithOpts(opt)
+			if err == nil {
+				t.Errorf("[%d] TryNewWithOpts with a width of %d returned no error", i, width)
+				continue
+			}
+
+			if !strings.Contains(err.Error(), fmt.Sprintf("received: %d", width)) {
+				t.Errorf("[%d] TryNewWithOpts with a width of %d\n\n  got error %q\n  want it to name the width", i, width, err)
+			}
+
+			if b != nil {
+				t.Errorf("[%d] TryNewWithOpts with a width of %d returned a bar alongside an error", i, width)
+			}
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("New with a width of 0 didn't panic")
+		}
+	}()
+
+	New(10, WithWidth(0))
+}
+
+func TestTryNewWithOptsRejectsReservedCustomVerbs(t *testing.T) {
+	ctx := Context{&ContextValue{verb: "percent", value: newStringish("x")}}
+
+	if _, err := TryNewWithOpts(WithDimensions(10, 10), WithContext(ctx)); err == nil {
+		t.Error("TryNewWithOpts(WithContext(:percent)) returned no error")
+	}
+}
+
+func TestNew(t *testing.T) {
+	ctx := Context{Ctx("name", "x")}
+
+	var testCases = []struct {
+		opts    []Option
+		width   int
+		display [5]string
+		format  string
+		verbs   []string
+	}{
+		{nil, 20, [5]string{"(", "█", "█", " ", ")"}, defaultFormat, []string{}},
+		{[]Option{WithWidth(40)}, 40, [5]string{"(", "█", "█", " ", ")"}, defaultFormat, []string{}},
+		{
+			[]Option{WithDisplay("[", "=", ">", "-", "]"), WithFormat(":bar :count")},
+			20,
+			[5]string{"[", "=", ">", "-", "]"},
+			":bar :count",
+			[]string{},
+		},
+		{
+			[]Option{WithFormat(":name :bar"), WithContext(ctx), WithWidth(5)},
+			5,
+			[5]string{"(", "█", "█", " ", ")"},
+			":name :bar",
+			[]string{"name"},
+		},
+	}
+
+	for i, testCase := range testCases {
+		b := New(30, testCase.opts...)
+
+		if b.total != 30 || b.width != testCase.width {
+			t.Errorf("[%d] total and width\n\n  got %d, %d\n  want %d, %d", i, b.total, b.width, 30, testCase.width)
+		}
+
+		display := [5]string{b.start, b.complete, b.head, b.incomplete, b.end}
+		if display != testCase.display {
+			t.Errorf("[%d] display\n\n  got %#v\n  want %#v", i, display, testCase.display)
+		}
+
+		if b.formatString != testCase.format {
+			t.Errorf("[%d] format\n\n  got %#v\n  want %#v", i, b.formatString, testCase.format)
+		}
+
+		if verbs := Context(b.context).customVerbs(); !reflect.DeepEqual(verbs, testCase.verbs) {
+			t.Errorf("[%d] custom verbs\n\n  got %#v\n  want %#v", i, verbs, testCase.verbs)
+		}
+	}
+}
+
+func TestNewRejectsInvalidWidth(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("New(10, WithWidth(0)) didn't panic")
+		}
+	}()
+
+	New(10, WithWidth(0))
+}
+
+func TestEmptyDisplayDefaults(t *testing.T) {
+	var testCases = []struct {
+		display  [5]string
+		expected string
+	}{
+		{[5]string{"", "", "", "", ""}, "██   "},
+		{[5]string{"[", "=", "", "", "]"}, "[==   ]"},
+		{[5]string{"[", "", ">", "-", "]"}, "[█>---]"},
+	}
+
+	for i, testCase := range testCases {
+		d := testCase.display
+		b := NewWithOpts(
+			WithDimensions(10, 5),
+			WithDisplay(d[0], d[1], d[2], d[3], d[4]),
+			WithFormat(":bar"),
+			WithOutput(discardOutput{}),
+		)
+		b.Set(4)
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%d] render with display %#v\n\n  got %#v\n  want %#v", i, d, got, testCase.expected)
+		}
+	}
+}
+
+func TestCheckCustomVerbs(t *testing.T) {
+	ctx := Context{Ctx("name", "x")}
+
+	if err := (tokens{customVerbToken{"name"}, spaceToken{}}).checkCustomVerbs(ctx); err != nil {
+		t.Errorf("checkCustomVerbs with a defined verb returned an error: %v", err)
+	}
+
+	if err := (tokens{customVerbToken{"missing"}}).checkCustomVerbs(ctx); err == nil {
+		t.Error("checkCustomVerbs with an undefined verb returned no error")
+	}
+}
+
+func TestUndefinedCustomVerbDoesNotWarn(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(2, 2), WithFormat(":count"), WithWriter(&buf))
+	b.format = tokens{customVerbToken{"missing"}}
+
+	for i := 0; i < 3; i++ {
+		if got, want := b.Render(), ":missing"; got != want {
+			t.Errorf("[%d] render of an undefined custom verb\n\n  got %#v\n  want %#v", i, got, want)
+		}
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("rendering an undefined custom verb wrote %#v", buf.String())
+	}
+}
@@ -1,12 +1,17 @@
 package bar
 
 import (
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/superhawk610/terminal"
 )
 
 // Output is a stand-in for a basic io.Writer that also exposes
 // a ClearLine() function to clear the current line and return the
-// cursor to the first index
+// cursor to the first index. An Output can report failures to write
+// through the bar's Err method by also implementing `Err() error`.
 type Output interface {
 	ClearLine()
 	Printf(format string, vals ...interface{})
@@ -30,3 +35,92 @@ func (s *stdout) ClearLine() {
 func (s *stdout) Printf(format string, vals ...interface{}) {
 	s.terminal.Overwritef(format, vals...)
 }
+
+// clearLine is the ANSI sequence to return the cursor to the first index
+// and erase the current line
+const clearLine = "\r\x1b[2K"
+
+type writerOutput struct {
+	w   io.Writer
+	err error
+}
+
+// ClearLine writes the ANSI sequence to clear the current line and return
+// the cursor to the first index
+func (o *writerOutput) ClearLine() {
+	io.WriteString(o, clearLine)
+}
+
+// Printf accepts a format string and any number of input values
+func (o *writerOutput) Printf(format string, vals ...interface{}) {
+	fmt.Fprintf(o, format, vals...)
+}
+
+// Write writes p directly to the underlying writer, unless writing to it has
+// already failed
+func (o *writerOutput) Write(p []byte) (int, error) {
+	if o.err != nil {
+		return 0, o.err
+	}
+
+	n, err := o.w.Write(p)
+	if err != nil {
+		o.err = err
+	}
+
+	return n, err
+}
+
+// Err returns the first error writing to the underlying writer, after which
+// nothing more is written to it
+func (o *writerOutput) Err() error {
+	return o.err
+}
+
+// isTerminal reports whether out writes to a terminal, which determines
+// whether escape sequences such as colors should be used. Outputs other than
+// stdout and files provided to WithWriter are assumed not to be terminals.
+func isTerminal(out Output) bool {
+	switch out := out.(type) {
+	case *stdout:
+		return isTerminalFile(os.Stdout)
+	case *writerOutput:
+		if f, ok := out.w.(*os.File); ok {
+			return isTerminalFile(f)
+		}
+	}
+
+	return false
+}
+
+// canInspect reports whether isTerminal can tell if out writes to a terminal,
+// which it can't for Outputs other than stdout and those from WithWriter
+func canInspect(out Output) bool {
+	switch out.(type) {
+	case *stdout, *writerOutput:
+		return true
+	}
+
+	return false
+}
+
+// outputWidth returns the number of columns of the terminal out writes to,
+// as well as a bool determining whether the width could be found
+func outputWidth(out Output) (int, bool) {
+	switch out := out.(type) {
+	case *stdout:
+		return fileWidth(os.Stdout)
+	case *writerOutput:
+		if f, ok := out.w.(*os.File); ok {
+			return fileWidth(f)
+		}
+	}
+
+	return 0, false
+}
+
+// isTerminalFile reports whether f is a character device, such as a terminal
+func isTerminalFile(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,589 @@
+package bar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(
+		WithDimensions(2, 2),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithFormat(":bar :count"),
+		WithWriter(&buf),
+		WithTTY(true),
+	)
+
+	b.Tick()
+	b.Interrupt("hello")
+	b.Tick()
+	b.Done()
+
+	expected := clearLine + "[> ] 1/2" +
+		clearLine + "hello\n" + clearLine + "[> ] 1/2" +
+		clearLine + "[=>] 2/2" +
+		clearLine + "[=>] 2/2\n"
+
+	if got := buf.String(); got != expected {
+		t.Errorf("output written to buffer\n\n  got %#v\n  want %#v", got, expected)
+	}
+}
+
+// captureStderr returns everything written to stderr while fn runs
+func captureStderr(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	stderr := os.Stderr
+	os.Stderr = w
+	fn()
+	os.Stderr = stderr
+	w.Close()
+
+	out, _ := ioutil.ReadAll(r)
+	return string(out)
+}
+
+func TestWarningsUseStderr(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(2, 2), WithFormat(":count"), WithWriter(&buf))
+	b.Done()
+	buf.Reset()
+
+	warning := captureStderr(t, b.Tick)
+	if !strings.Contains(warning, "attempted to call Tick on a closed bar") {
+		t.Errorf("warning was not written to stderr, got %#v", warning)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("warning was written to the bar's writer: %#v", buf.String())
+	}
+}
+
+func TestWithMinInterval(t *testing.T) {
+	var buf bytes.Buffer
+
+	clock := newFakeClock()
+	b := NewWithOpts(
+		WithDimensions(10, 10),
+		WithFormat(":count"),
+		WithWriter(&buf),
+		WithTTY(true),
+		WithMinInterval(100*time.Millisecond),
+	)
+	b.now = clock.now
+
+	frames := func() []string {
+		return strings.Split(buf.String(), clearLine)[1:]
+	}
+
+	b.Tick()
+	for i := 0; i < 4; i++ {
+		clock.advance(20 * time.Millisecond)
+		b.Tick()
+	}
+
+	if got, want := frames(), []string{"1/10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("frames within the interval\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	clock.advance(20 * time.Millisecond)
+	b.Tick()
+
+	if got, want := frames(), []string{"1/10", "6/10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("frames after the interval\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	b.Tick()
+	b.Set(10)
+
+	if got, want := frames(), []string{"1/10", "6/10", "10/10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("frames after completing\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestWithMinIntervalAlwaysDrawsDone(t *testing.T) {
+	var buf bytes.Buffer
+
+	clock := newFakeClock()
+	b := NewWithOpts(
+		WithDimensions(10, 10),
+		WithFormat(":count"),
+		WithWriter(&buf),
+		WithTTY(true),
+		WithMinInterval(time.Hour),
+	)
+	b.now = clock.now
+
+	b.Tick()
+	b.Tick()
+	b.Tick()
+	b.Done()
+
+	if got, want := buf.String(), clearLine+"1/10"+clearLine+"3/10\n"; got != want {
+		t.Errorf("output\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestWithMinIntervalIndeterminate(t *testing.T) {
+	var buf bytes.Buffer
+
+	clock := newFakeClock()
+	b := NewWithOpts(
+		WithDimensions(0, 10),
+		WithFormat(":progress"),
+		WithWriter(&buf),
+		WithTTY(true),
+		WithMinInterval(100*time.Millisecond),
+	)
+	b.now = clock.now
+
+	b.Tick()
+	for i := 0; i < 4; i++ {
+		clock.advance(20 * time.Millisecond)
+		b.Tick()
+	}
+
+	clock.advance(20 * time.Millisecond)
+	b.Tick()
+	b.Done()
+
+	if got, want := buf.String(), clearLine+"1"+clearLine+"6"+clearLine+"6\n"; got != want {
+		t.Errorf("output\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestWithFitWidth(t *testing.T) {
+	var testCases = []struct {
+		format   string
+		cols     int
+		ok       bool
+		expected string
+	}{
+		{":bar", 12, true, "[=>        ]"},
+		{":bar :percent", 16, true, "[=>      ] 25.0%"},
+		{"go :bar :count", 20, true, "go [=>        ] 5/20"},
+		{":bar :bar", 21, true, "[=>      ] [=>      ]"},
+		{":bar(4) :bar", 20, true, "[>   ] [=>         ]"},
+		{":bar :percent", 0, false, "[=>        ] 25.0%"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(20, 10), WithDisplay("[", "=", ">", " ", "]"), WithFormat(testCase.format), WithFitWidth())
+		b.progress = 5
+		b.termWidth = func() (int, bool) {
+			return testCase.cols, testCase.ok
+		}
+
+		got := b.Render()
+		if got != testCase.expected {
+			t.Errorf("[%d] %#v fit to %d columns\n\n  got %#v\n  want %#v", i, testCase.format, testCase.cols, got, testCase.expected)
+		}
+
+		if testCase.ok && displayWidth(got) != testCase.cols {
+			t.Errorf("[%d] %#v rendered %d columns wide, want %d", i, testCase.format, displayWidth(got), testCase.cols)
+		}
+	}
+}
+
+func TestWidthPercent(t *testing.T) {
+	var testCases = []struct {
+		format   string
+		percent  int
+		cols     int
+		ok       bool
+		expected string
+	}{
+		{":bar", 50, 16, true, "[=>      ]"},
+		{":bar", 25, 16, true, "[>   ]"},
+		{":bar :percent", 100, 12, true, "[==>         ] 25.0%"},
+		{":bar(4) :bar", 50, 12, true, "[>   ] [>     ]"},
+		{":bar", 1, 12, true, "[>]"},
+		{":bar", 50, 0, false, "[=>        ]"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(20, 10), WithDisplay("[", "=", ">", " ", "]"), WithFormat(testCase.format), WithWidthPercent(testCase.percent))
+		b.progress = 5
+		b.termWidth = func() (int, bool) {
+			return testCase.cols, testCase.ok
+		}
+
+		if got := b.Render(); got != testCase.expected {
+			t.Errorf("[%d] %#v at %d%% of %d columns\n\n  got %#v\n  want %#v", i, testCase.format, testCase.percent, testCase.cols, got, testCase.expected)
+		}
+	}
+
+	// the terminal's width is checked on every render
+	cols := 40
+	b := newTestBar(newFakeClock(), WithDimensions(20, 10), WithDisplay("[", "=", ">", " ", "]"), WithFormat(":bar"), WithWidthPercent(10))
+	b.progress = 10
+	b.termWidth = func() (int, bool) {
+		return cols, true
+	}
+
+	for _, want := range []string{"[=>  ]", "[> ]"} {
+		if got := b.Render(); got != want {
+			t.Errorf("render at 10%% of %d columns\n\n  got %#v\n  want %#v", cols, got, want)
+		}
+		cols = 20
+	}
+}
+
+func TestFill(t *testing.T) {
+	var testCases = []struct {
+		format   string
+		fit      bool
+		cols     int
+		ok       bool
+		expected string
+	}{
+		{":bar:fill:count", false, 20, true, "[>   ]          5/20"},
+		{":bar :fill :count", false, 20, true, "[>   ]          5/20"},
+		{":fill:count", false, 10, true, "      5/20"},
+		{":count:fill", false, 10, true, "5/20      "},
+		{":fill:count:fill", false, 11, true, "    5/20   "},
+		{":bar:fill:count", false, 8, true, "[>   ]5/20"},
+		{":bar:fill:count", false, 0, false, "[>   ]5/20"},
+		{":bar :fill :count", true, 20, true, "[==>         ]  5/20"},
+		{":bar(4) :fill(color=red) :count", false, 20, true, "[>   ]          5/20"},
+		{"下载:fill:count", false, 10, true, "下载  5/20"},
+	}
+
+	for i, testCase := range testCases {
+		opts := []Option{WithDimensions(20, 4), WithDisplay("[", "=", ">", " ", "]"), WithFormat(testCase.format)}
+		if testCase.fit {
+			opts = append(opts, WithFitWidth())
+		}
+
+		b := newTestBar(newFakeClock(), opts...)
+		b.progress = 5
+		b.termWidth = func() (int, bool) {
+			return testCase.cols, testCase.ok
+		}
+
+		got := b.Render()
+		if got != testCase.expected {
+			t.Errorf("[%d] %#v filled to %d columns\n\n  got %#v\n  want %#v", i, testCase.format, testCase.cols, got, testCase.expected)
+		}
+
+		if testCase.ok && testCase.cols >= 10 && displayWidth(got) != testCase.cols {
+			t.Errorf("[%d] %#v rendered %d columns wide, want %d", i, testCase.format, displayWidth(got), testCase.cols)
+		}
+	}
+}
+
+func TestWidthPercentMustBeValid(t *testing.T) {
+	for _, percent := range []int{-1, 101} {
+		if _, err := TryNewWithOpts(WithWidthPercent(percent)); err == nil {
+			t.Errorf("TryNewWithOpts(WithWidthPercent(%d)) returned no error", percent)
+		}
+	}
+}
+
+func TestFinish(t *testing.T) {
+	var buf bytes.Buffer
+
+	calls := 0
+	b := NewWithOpts(
+		WithDimensions(10, 4),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithFormat(":bar :percent"),
+		WithWriter(&buf),
+		WithTTY(true),
+		WithMinInterval(time.Hour),
+		WithCallback(func() { calls++ }),
+	)
+
+	b.Add(3)
+	b.Add(3)
+	b.Finish()
+	b.Finish()
+
+	expected := clearLine + "[>   ] 30.0%" + clearLine + "[===>] 100.0%\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("output after Finish\n\n  got %#v\n  want %#v", got, expected)
+	}
+
+	if calls != 1 {
+		t.Errorf("callback calls after finishing twice\n\n  got %d\n  want %d", calls, 1)
+	}
+}
+
+func TestClear(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(2, 2), WithFormat(":count"), WithWriter(&buf))
+	b.Clear()
+
+	if got := buf.String(); got != "" {
+		t.Errorf("output after clearing a non-terminal\n\n  got %#v\n  want %#v", got, "")
+	}
+
+	// pretend that the buffer is a terminal
+	b.tty = true
+	b.Tick()
+	b.Clear()
+
+	if got, want := buf.String(), clearLine+"1/2"+clearLine; got != want {
+		t.Errorf("output after clearing a terminal\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestNewlineOnFinish(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		var buf bytes.Buffer
+
+		b := NewWithOpts(WithDimensions(3, 3), WithFormat(":count"), WithWriter(&buf), WithTTY(true), WithNewlineOnFinish(enabled))
+		b.Tick()
+		b.Tick()
+		b.Finish()
+
+		frames := strings.Split(buf.String(), clearLine)
+		if frames[0] != "" {
+			t.Errorf("output before the first frame (newline on finish: %v)\n\n  got %#v\n  want %#v", enabled, frames[0], "")
+		}
+
+		frames = frames[1:]
+		for i, frame := range frames[:len(frames)-1] {
+			if strings.ContainsAny(frame, "\r\n") {
+				t.Errorf("[%d] intermediate frame (newline on finish: %v) contains a line break: %#v", i, enabled, frame)
+			}
+		}
+
+		final := "3/3"
+		if enabled {
+			final += "\n"
+		}
+
+		if got := frames[len(frames)-1]; got != final {
+			t.Errorf("final frame (newline on finish: %v)\n\n  got %#v\n  want %#v", enabled, got, final)
+		}
+	}
+}
+
+func TestLineMode(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(
+		WithDimensions(20, 4),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithFormat(":bar :count"),
+		WithWriter(&buf),
+		WithTTY(false),
+	)
+
+	for i := 0; i < 12; i++ {
+		b.Tick()
+	}
+	b.Interrupt("hello")
+	b.Done()
+
+	expected := strings.Join([]string{
+		"[>   ] 1/20",
+		"[>   ] 2/20",
+		"[>   ] 4/20",
+		"[>   ] 6/20",
+		"[>   ] 8/20",
+		"[=>  ] 10/20",
+		"[=>  ] 12/20",
+		"hello",
+		"",
+	}, "\n")
+
+	if got := buf.String(); got != expected {
+		t.Errorf("output in line mode\n\n  got %#v\n  want %#v", got, expected)
+	}
+
+	buf.Reset()
+	b.Reset()
+	b.Set(20)
+	b.Finish()
+
+	if got, want := buf.String(), "[===>] 20/20\n"; got != want {
+		t.Errorf("output when finishing in line mode\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestLineModeIndeterminate(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(0, 4), WithFormat(":count"), WithWriter(&buf), WithTTY(false))
+	b.Tick()
+	b.Tick()
+	b.Done()
+
+	if got, want := buf.String(), "1/0\n2/0\n"; got != want {
+		t.Errorf("output of an indeterminate bar in line mode\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+// recordingOutput is an Output that records each call made to it
+type recordingOutput struct {
+	calls []string
+}
+
+func (o *recordingOutput) ClearLine() {
+	o.calls = append(o.calls, "ClearLine")
+}
+
+func (o *recordingOutput) Printf(format string, vals ...interface{}) {
+	o.calls = append(o.calls, fmt.Sprintf(format, vals...))
+}
+
+func TestCustomOutputIsRedrawn(t *testing.T) {
+	out := &recordingOutput{}
+	b := NewWithOpts(WithDimensions(2, 2), WithFormat(":count"), WithOutput(out), WithColors(Red, Green))
+	b.Tick()
+	b.Tick()
+
+	if got, want := out.calls, []string{"ClearLine", "1/2", "ClearLine", "2/2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls to a custom output\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if b.colorize {
+		t.Error("bar drawn to a custom output is colored without WithTTY or WithForceColor")
+	}
+
+	out = &recordingOutput{}
+	b = NewWithOpts(WithDimensions(2, 2), WithFormat(":count"), WithOutput(out), WithTTY(false))
+	b.Tick()
+	b.Tick()
+
+	if got, want := out.calls, []string{"1/2\n", "2/2\n"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls to a custom output with WithTTY(false)\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestCustomOutputGetsEverything(t *testing.T) {
+	// interruptions and the newline on finishing go to the output too,
+	// rather than to stdout
+	out := &recordingOutput{}
+	b := NewWithOpts(WithDimensions(2, 2), WithFormat(":count"), WithOutput(out))
+	b.Tick()
+	b.Interrupt("hello")
+	b.Done()
+
+	want := []string{"ClearLine", "1/2", "ClearLine", "hello\n", "ClearLine", "1/2", "ClearLine", "1/2", "\n"}
+	if got := out.calls; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls to a custom output\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestBlankFormatsDrawNothing(t *testing.T) {
+	for _, format := range []string{"", " ", "   "} {
+		var buf bytes.Buffer
+
+		b := NewWithOpts(WithDimensions(2, 2), WithFormat(format), WithWriter(&buf), WithTTY(true))
+		b.Tick()
+		b.Interrupt("hello")
+		b.Done()
+
+		if got, want := buf.String(), clearLine+"hello\n"; got != want {
+			t.Errorf("output with format %#v\n\n  got %#v\n  want %#v", format, got, want)
+		}
+	}
+}
+
+func TestBlankFormatWithPrefixIsDrawn(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(2, 2), WithFormat(""), WithPrefix("Loading"), WithSuffix("..."), WithWriter(&buf), WithTTY(true))
+	b.Tick()
+
+	if got, want := buf.String(), clearLine+"Loading..."; got != want {
+		t.Errorf("output with a blank format and a prefix and suffix\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestHideUntilStart(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(10, 4), WithFormat(":count"), WithWriter(&buf), WithTTY(true), WithRefreshInterval(time.Millisecond), WithHideUntilStart())
+	b.Set(0)
+	b.Update(0, nil)
+	b.Add(0)
+	b.Start()
+	time.Sleep(10 * time.Millisecond)
+	b.Stop()
+
+	if got := buf.String(); got != "" {
+		t.Fatalf("output before any progress\n\n  got %#v\n  want %#v", got, "")
+	}
+
+	// once it's been shown, the bar is drawn even without progress
+	b.Add(1)
+	b.Set(0)
+
+	if got, want := buf.String(), clearLine+"1/10"+clearLine+"0/10"; got != want {
+		t.Errorf("output after the first progress\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	// a bar that's done before it starts is never drawn
+	buf.Reset()
+	b.Reset()
+	b.Done()
+
+	if got := buf.String(); got != "" {
+		t.Errorf("output of a bar done before it started\n\n  got %#v\n  want %#v", got, "")
+	}
+}
+
+// brokenWriter fails every write, counting how many were attempted
+type brokenWriter struct {
+	writes int
+}
+
+func (w *brokenWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriteErrors(t *testing.T) {
+	var w brokenWriter
+
+	b := NewWithOpts(WithDimensions(10, 4), WithFormat(":count"), WithWriter(&w), WithTTY(true))
+	if err := b.Err(); err != nil {
+		t.Fatalf("Err before the bar was drawn returned unexpected error: %v", err)
+	}
+
+	b.Tick()
+	if err := b.Err(); err == nil || err.Error() != "broken pipe" {
+		t.Errorf("Err after a failed write\n\n  got %v\n  want %v", err, "broken pipe")
+	}
+
+	b.Tick()
+	if err := b.Finish(); err == nil || err.Error() != "broken pipe" {
+		t.Errorf("Finish after a failed write\n\n  got %v\n  want %v", err, "broken pipe")
+	}
+
+	if w.writes != 1 {
+		t.Errorf("writes attempted after the first one failed\n\n  got %d\n  want %d", w.writes-1, 0)
+	}
+}
+
+func TestFinishWithoutWriteErrors(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(10, 4), WithFormat(":count"), WithWriter(&buf), WithTTY(true))
+	if err := b.Finish(); err != nil {
+		t.Errorf("Finish returned unexpected error: %v", err)
+	}
+
+	if err := b.Finish(); err != nil {
+		t.Errorf("Finish on a finished bar returned unexpected error: %v", err)
+	}
+}
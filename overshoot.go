@@ -0,0 +1,30 @@
+package bar
+
+import "fmt"
+
+// OvershootPolicy determines what happens when the bar's progress is set
+// past its total
+type OvershootPolicy int
+
+// Ways in which progress past the bar's total can be handled
+const (
+	// OvershootClamp clamps the progress to the total (the default)
+	OvershootClamp OvershootPolicy = iota
+	// OvershootError rejects the update, leaving the progress unchanged; Add
+	// and Set return an error, and other updates print a warning
+	OvershootError
+)
+
+// overshot checks progress against the bar's total according to its
+// overshoot policy, returning the progress to set; the caller must hold b.mu
+func (b *Bar) overshot(progress int) (int, error) {
+	if b.indeterminate() || progress <= b.total {
+		return progress, nil
+	}
+
+	if b.overshoot == OvershootError {
+		return 0, fmt.Errorf("bar: progress %d exceeds the total of %d", progress, b.total)
+	}
+
+	return b.total, nil
+}
@@ -0,0 +1,88 @@
+package bar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOvershootClamp(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithFormat(":count"))
+
+	if err := b.Add(7); err != nil {
+		t.Fatalf("Add(7) returned an error: %v", err)
+	}
+
+	if err := b.Add(7); err != nil {
+		t.Errorf("Add past the total returned an error: %v", err)
+	}
+
+	if b.progress != 10 {
+		t.Errorf("Add past the total set the progress to %d, want 10", b.progress)
+	}
+
+	if err := b.Set(25); err != nil {
+		t.Errorf("Set past the total returned an error: %v", err)
+	}
+
+	if got, want := b.Render(), "10/10"; got != want {
+		t.Errorf("render after overshooting\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestOvershootError(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(10, 4), WithFormat(":count"), WithWriter(&buf), WithOvershootPolicy(OvershootError))
+
+	if err := b.Set(10); err != nil {
+		t.Fatalf("Set to the total returned an error: %v", err)
+	}
+
+	var testCases = []struct {
+		method string
+		update func() error
+	}{
+		{"Add", func() error { return b.Add(1) }},
+		{"Set", func() error { return b.Set(25) }},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.update()
+		if err == nil {
+			t.Errorf("%s past the total returned no error", testCase.method)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), "exceeds the total of 10") {
+			t.Errorf("%s past the total\n\n  got error %q\n  want it to name the total", testCase.method, err)
+		}
+
+		if b.progress != 10 {
+			t.Errorf("%s past the total changed the progress to %d", testCase.method, b.progress)
+		}
+	}
+
+	// methods that can't return the error print a warning instead
+	got := captureStderr(t, b.Tick)
+
+	if b.progress != 10 {
+		t.Errorf("Tick past the total changed the progress to %d", b.progress)
+	}
+
+	if !strings.Contains(got, "progress 11 exceeds the total of 10") {
+		t.Errorf("Tick past the total didn't print a warning: %#v", got)
+	}
+}
+
+func TestOvershootIndeterminate(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(0, 4), WithOvershootPolicy(OvershootError))
+
+	if err := b.Set(100); err != nil {
+		t.Errorf("Set on an indeterminate bar returned an error: %v", err)
+	}
+
+	if b.progress != 100 {
+		t.Errorf("Set on an indeterminate bar set the progress to %d, want 100", b.progress)
+	}
+}
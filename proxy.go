@@ -0,0 +1,56 @@
+package bar
+
+import (
+	"io"
+)
+
+// proxyWriter advances a bar by the number of bytes written through it
+type proxyWriter struct {
+	bar *Bar
+	w   io.Writer
+}
+
+// proxyReader advances a bar by the number of bytes read through it
+type proxyReader struct {
+	bar *Bar
+	r   io.Reader
+}
+
+// ProxyWriter returns a writer that writes to w and advances the bar by the
+// number of bytes written, so that the progress of copying to w (such as
+// with io.Copy) is shown by the bar
+func (b *Bar) ProxyWriter(w io.Writer) io.Writer {
+	return &proxyWriter{bar: b, w: w}
+}
+
+// Write writes p to the underlying writer, advancing the bar by the number
+// of bytes it accepted (even if it returns an error after a partial write)
+func (p *proxyWriter) Write(buf []byte) (int, error) {
+	n, err := p.w.Write(buf)
+	if n > 0 {
+		p.bar.Add(n)
+	}
+
+	return n, err
+}
+
+// ProxyReader returns a reader that reads from r and advances the bar by
+// the number of bytes read, finishing the bar once r is exhausted
+func (b *Bar) ProxyReader(r io.Reader) io.Reader {
+	return &proxyReader{bar: b, r: r}
+}
+
+// Read reads from the underlying reader into buf, advancing the bar by the
+// number of bytes read; the bytes and error are returned unchanged
+func (p *proxyReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	if n > 0 {
+		p.bar.Add(n)
+	}
+
+	if err == io.EOF {
+		p.bar.Finish()
+	}
+
+	return n, err
+}
@@ -0,0 +1,100 @@
+package bar
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// shortWriter accepts at most limit bytes in total, failing afterwards
+type shortWriter struct {
+	limit int
+	buf   bytes.Buffer
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		w.buf.Write(p[:w.limit])
+		n := w.limit
+		w.limit = 0
+		return n, errors.New("short write")
+	}
+
+	w.limit -= len(p)
+	return w.buf.Write(p)
+}
+
+func TestProxyWriter(t *testing.T) {
+	src := bytes.Repeat([]byte("0123456789"), 1000)
+
+	b := newTestBar(newFakeClock(), WithDimensions(len(src), 10), WithFormat(":percent"))
+
+	var dst bytes.Buffer
+	n, err := io.Copy(b.ProxyWriter(&dst), bytes.NewReader(src))
+	if err != nil || n != int64(len(src)) {
+		t.Fatalf("io.Copy through ProxyWriter returned %d, %v", n, err)
+	}
+
+	if !bytes.Equal(dst.Bytes(), src) {
+		t.Error("bytes written through ProxyWriter don't match the source")
+	}
+
+	if got, want := b.Render(), "100.0%"; got != want {
+		t.Errorf("render after copying\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestProxyWriterPartialWrite(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(10, 10))
+	w := &shortWriter{limit: 4}
+
+	n, err := b.ProxyWriter(w).Write([]byte("0123456789"))
+	if n != 4 || err == nil {
+		t.Errorf("partial write through ProxyWriter returned %d, %v", n, err)
+	}
+
+	if b.progress != 4 {
+		t.Errorf("progress after a partial write\n\n  got %d\n  want %d", b.progress, 4)
+	}
+}
+
+func TestProxyReader(t *testing.T) {
+	src := bytes.Repeat([]byte("0123456789"), 100)
+
+	calls := 0
+	b := newTestBar(newFakeClock(), WithDimensions(len(src), 10), WithCallback(func() { calls++ }))
+	r := b.ProxyReader(bytes.NewReader(src))
+
+	buf := make([]byte, 64)
+	read := 0
+	for {
+		n, err := r.Read(buf)
+		read += n
+
+		if b.progress != read {
+			t.Fatalf("progress after reading %d bytes\n\n  got %d\n  want %d", read, b.progress, read)
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("unexpected error reading through ProxyReader: %v", err)
+		}
+
+		if n != len(buf) && read != len(src) {
+			t.Errorf("short read of %d bytes through ProxyReader", n)
+		}
+	}
+
+	// reading again after EOF returns EOF without finishing the bar twice
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("read after EOF returned %d, %v", n, err)
+	}
+
+	if read != len(src) || !b.closed || calls != 1 {
+		t.Errorf("after reading to EOF: read %d bytes, closed %v, callbacks %d", read, b.closed, calls)
+	}
+}
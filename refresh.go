@@ -0,0 +1,92 @@
+package bar
+
+import "time"
+
+// defaultRefreshInterval is how often a bar started with Start is redrawn
+// when no interval is given with WithRefreshInterval
+const defaultRefreshInterval = 100 * time.Millisecond
+
+// refresher is a goroutine that redraws a bar at a fixed interval
+type refresher struct {
+	stop, stopped chan struct{}
+}
+
+// running reports whether the refresher's goroutine hasn't exited yet
+func (r *refresher) running() bool {
+	select {
+	case <-r.stopped:
+		return false
+	default:
+		return true
+	}
+}
+
+// Start redraws the bar in the background at a fixed interval (set with
+// WithRefreshInterval), so that verbs that change with time rather than
+// progress, such as :elapsed and :spinner, keep moving between updates.
+// Redrawing stops once the bar is closed or Stop is called. Bars that are
+// written line by line or as JSON aren't redrawn, since there's nothing to
+// animate. Calling Start on a bar that's already being redrawn does nothing.
+func (b *Bar) Start() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed || (b.refresher != nil && b.refresher.running()) {
+		return
+	}
+
+	interval := b.refreshInterval
+	if interval == 0 {
+		interval = defaultRefreshInterval
+	}
+
+	r := &refresher{stop: make(chan struct{}), stopped: make(chan struct{})}
+	b.refresher = r
+
+	done := b.doneChan()
+	go func() {
+		defer close(r.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				b.refresh()
+			case <-r.stop:
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops redrawing the bar in the background, waiting for the goroutine
+// started by Start to exit. It does nothing if the bar isn't being redrawn.
+func (b *Bar) Stop() {
+	b.mu.Lock()
+	r := b.refresher
+	b.refresher = nil
+	b.mu.Unlock()
+
+	if r == nil {
+		return
+	}
+
+	close(r.stop)
+	<-r.stopped
+}
+
+// refresh redraws the bar if it's still open and drawn on a terminal
+func (b *Bar) refresh() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed || b.mode != ModeBar || b.lineMode() {
+		return
+	}
+
+	b.write()
+}
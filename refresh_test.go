@@ -0,0 +1,83 @@
+package bar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lockedFrames returns the frames written to buf by b, reading it while
+// holding the bar's lock since the bar may be drawn in the background
+func lockedFrames(b *Bar, buf *bytes.Buffer) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return strings.Split(buf.String(), clearLine)[1:]
+}
+
+func TestStart(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(
+		WithDimensions(10, 4),
+		WithFormat(":spinner"),
+		WithSpinner("a", "b", "c"),
+		WithWriter(&buf),
+		WithTTY(true),
+		WithRefreshInterval(time.Millisecond),
+	)
+	b.Start()
+	b.Start()
+
+	deadline := time.Now().Add(time.Second)
+	for len(lockedFrames(b, &buf)) < 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	b.Stop()
+	frames := lockedFrames(b, &buf)
+
+	if len(frames) < 4 {
+		t.Fatalf("bar was only drawn %d times in the background", len(frames))
+	}
+
+	if got, want := strings.Join(frames[:4], ""), "abca"; got != want {
+		t.Errorf("spinner frames drawn in the background\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if got := lockedFrames(b, &buf); len(got) != len(frames) {
+		t.Errorf("bar was drawn %d more times after Stop", len(got)-len(frames))
+	}
+
+	if b.progress != 0 {
+		t.Errorf("drawing the bar in the background changed its progress to %d", b.progress)
+	}
+}
+
+func TestStartStopsWhenClosed(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(1, 4), WithFormat(":spinner"), WithWriter(&buf), WithTTY(true), WithRefreshInterval(time.Millisecond))
+	b.Start()
+	r := b.refresher
+
+	b.Done()
+
+	select {
+	case <-r.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("finishing the bar didn't stop it being drawn in the background")
+	}
+
+	// stopping a bar that has already stopped by itself shouldn't block
+	b.Stop()
+	b.Stop()
+}
+
+func TestRefreshIntervalMayNotBeNegative(t *testing.T) {
+	if _, err := TryNewWithOpts(WithRefreshInterval(-time.Second)); err == nil {
+		t.Error("TryNewWithOpts with a negative refresh interval returned no error")
+	}
+}
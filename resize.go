@@ -0,0 +1,46 @@
+package bar
+
+import (
+	"os"
+	"os/signal"
+)
+
+// notifyResize returns a channel that receives a signal each time the
+// terminal is resized, or nil on platforms where that can't be detected
+func notifyResize() chan os.Signal {
+	sigs := resizeSignals()
+	if len(sigs) == 0 {
+		return nil
+	}
+
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, sigs...)
+
+	return resized
+}
+
+// watchResize redraws the bar each time a signal is received from resized,
+// until the bar is closed; it does nothing if resized is nil. The caller must
+// hold b.mu.
+func (b *Bar) watchResize(resized chan os.Signal) {
+	if resized == nil {
+		return
+	}
+
+	b.watchingResize = true
+	done := b.doneChan()
+
+	go func() {
+		defer signal.Stop(resized)
+
+		for {
+			select {
+			case <-resized:
+				// the terminal's width is measured again as the bar is drawn
+				b.refresh()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package bar
+
+import (
+	"os"
+)
+
+// resizeSignals returns no signals on platforms where terminal resizes
+// can't be detected
+func resizeSignals() []os.Signal {
+	return nil
+}
@@ -0,0 +1,85 @@
+package bar
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRedrawOnResize(t *testing.T) {
+	var buf bytes.Buffer
+
+	cols := 12
+	b := NewWithOpts(
+		WithDimensions(20, 10),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithFormat(":bar"),
+		WithWriter(&buf),
+		WithTTY(true),
+		WithMinInterval(time.Hour),
+		WithFitWidth(),
+		WithRedrawOnResize(),
+	)
+	b.termWidth = func() (int, bool) {
+		return cols, true
+	}
+
+	// any signal stands in for SIGWINCH, so that this runs on every platform
+	resized := make(chan os.Signal, 1)
+	watched := 0
+	b.notifyResize = func() chan os.Signal {
+		watched++
+		return resized
+	}
+
+	if watched != 0 {
+		t.Fatal("resizes were watched before the bar was drawn")
+	}
+
+	b.Set(10)
+
+	b.mu.Lock()
+	cols = 8
+	b.mu.Unlock()
+	resized <- os.Interrupt
+
+	deadline := time.Now().Add(time.Second)
+	for len(lockedFrames(b, &buf)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	frames := lockedFrames(b, &buf)
+	if len(frames) != 2 {
+		t.Fatalf("bar was drawn %d times, want 2 (once when set and once when resized)", len(frames))
+	}
+
+	if got, want := frames[1], "[==>   ]"; got != want {
+		t.Errorf("render after a resize\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	b.Finish()
+	resized <- os.Interrupt
+
+	time.Sleep(10 * time.Millisecond)
+	if got := lockedFrames(b, &buf); len(got) != 3 {
+		t.Errorf("bar was drawn %d times after being finished and resized, want 3", len(got))
+	}
+
+	// a reset bar watches again once it's drawn
+	select {
+	case <-resized:
+	default:
+	}
+
+	b.Reset()
+	b.Set(1)
+
+	b.mu.Lock()
+	if watched != 2 {
+		t.Errorf("resizes were watched %d times after resetting the bar, want 2", watched)
+	}
+	b.mu.Unlock()
+
+	b.Finish()
+}
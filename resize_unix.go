@@ -0,0 +1,14 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package bar
+
+import (
+	"os"
+	"syscall"
+)
+
+// resizeSignals returns the signals sent when the terminal is resized
+func resizeSignals() []os.Signal {
+	return []os.Signal{syscall.SIGWINCH}
+}
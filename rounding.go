@@ -0,0 +1,34 @@
+package bar
+
+import "math"
+
+// Rounding determines how the bar's fill is rounded to whole cells
+type Rounding int
+
+// Ways in which the bar's fill can be rounded
+const (
+	// RoundingFloor rounds down, so a cell is only filled once it's been
+	// completed (the default)
+	RoundingFloor Rounding = iota
+	// RoundingNearest rounds to the nearest cell
+	RoundingNearest
+	// RoundingCeil rounds up, so a cell is filled as soon as it's started
+	RoundingCeil
+)
+
+// roundingTolerance is ignored when rounding, since it's the kind of error
+// that floating point arithmetic leaves behind (so that 29% of 100 cells is
+// 29 cells rather than 28.999...)
+const roundingTolerance = 1e-9
+
+// apply rounds v to a whole number
+func (r Rounding) apply(v float64) float64 {
+	switch r {
+	case RoundingNearest:
+		return math.Round(v)
+	case RoundingCeil:
+		return math.Ceil(v - roundingTolerance)
+	default:
+		return math.Floor(v + roundingTolerance)
+	}
+}
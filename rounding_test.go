@@ -0,0 +1,48 @@
+package bar
+
+import "testing"
+
+func TestCellRounding(t *testing.T) {
+	var testCases = []struct {
+		progress int
+		rounding Rounding
+		expected string
+	}{
+		{98, RoundingFloor, "[========> ]"},
+		{98, RoundingNearest, "[=========>]"},
+		{98, RoundingCeil, "[=========>]"},
+		{92, RoundingFloor, "[========> ]"},
+		{92, RoundingNearest, "[========> ]"},
+		{92, RoundingCeil, "[=========>]"},
+		{15, RoundingFloor, "[>         ]"},
+		{15, RoundingNearest, "[=>        ]"},
+		{15, RoundingCeil, "[=>        ]"},
+		{30, RoundingFloor, "[==>       ]"},
+		{30, RoundingNearest, "[==>       ]"},
+		{30, RoundingCeil, "[==>       ]"},
+		{0, RoundingCeil, "[>         ]"},
+		{100, RoundingFloor, "[=========>]"},
+	}
+
+	for i, testCase := range testCases {
+		b := newTestBar(newFakeClock(), WithDimensions(100, 10), WithDisplay("[", "=", ">", " ", "]"), WithCellRounding(testCase.rounding))
+		b.progress = testCase.progress
+
+		if got := (barToken{}).print(b); got != testCase.expected {
+			t.Errorf("[%d] barToken.print at %d%% with rounding %d\n\n  got %#v\n  want %#v", i, testCase.progress, testCase.rounding, got, testCase.expected)
+		}
+	}
+}
+
+func TestConsistentCellRounding(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(1000, 10), WithCellRounding(RoundingCeil), WithConsistentRounding())
+	b.progress = 981
+
+	if got, want := (percentToken{precision: 0}).print(b), "99%"; got != want {
+		t.Errorf("percentToken.print rounded up\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if got, want := b.cells(10), 10; got != want {
+		t.Errorf("cells rounded up\n\n  got %d\n  want %d", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+package bar
+
+import (
+	"time"
+)
+
+// Snapshot is a consistent view of a bar's state at a single point in time,
+// which is safe to read from any goroutine
+type Snapshot struct {
+	// Progress and Total are the bar's current progress and its total
+	Progress, Total int
+	// Percent is the fraction of the total that has been completed, from 0
+	// to 1 (see Bar.Percent)
+	Percent float64
+	// Rate is the bar's rate of progress, in items per second; it's zero
+	// until a rate has been established
+	Rate float64
+	// ETA is the estimated time remaining, if ETAKnown is true
+	ETA      time.Duration
+	ETAKnown bool
+	// Elapsed is the time since the bar's first progress update, excluding
+	// any time spent paused
+	Elapsed time.Duration
+	// Finished reports whether Done or Finish has been called
+	Finished bool
+}
+
+// Snapshot returns the bar's current state, captured all at once so that
+// its values are consistent with each other even while the bar is being
+// updated from other goroutines
+func (b *Bar) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.snapshot()
+}
+
+// snapshot is like Snapshot; the caller must hold b.mu
+func (b *Bar) snapshot() Snapshot {
+	s := Snapshot{
+		Progress: b.progress,
+		Total:    b.total,
+		Percent:  b.prog(),
+		Elapsed:  b.elapsed(),
+		Finished: b.closed,
+	}
+
+	if b.hasRate() {
+		s.Rate = b.rate
+	}
+
+	s.ETA, s.ETAKnown = b.estimate()
+
+	return s
+}
@@ -0,0 +1,55 @@
+package bar
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBar(clock, WithDimensions(10, 10))
+
+	clock.advance(time.Second)
+	b.Tick()
+	clock.advance(time.Second)
+	b.Tick()
+
+	expected := Snapshot{
+		Progress: 2,
+		Total:    10,
+		Percent:  0.2,
+		Rate:     0.5,
+		ETA:      18 * time.Second,
+		ETAKnown: true,
+		Elapsed:  time.Second,
+	}
+
+	if got := b.Snapshot(); got != expected {
+		t.Errorf("Snapshot()\n\n  got %+v\n  want %+v", got, expected)
+	}
+}
+
+func TestSnapshotConcurrentReads(t *testing.T) {
+	b := newTestBar(newFakeClock(), WithDimensions(1000, 10))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			b.Tick()
+		}
+	}()
+
+	last := 0
+	for s := b.Snapshot(); s.Progress < 1000; s = b.Snapshot() {
+		if s.Progress < last || s.Percent != float64(s.Progress)/1000 {
+			t.Fatalf("inconsistent snapshot %+v after progress %d", s, last)
+		}
+
+		last = s.Progress
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package bar
+
+import (
+	"os"
+)
+
+// fileWidth always reports that the terminal width is unknown on platforms
+// where it can't be queried
+func fileWidth(f *os.File) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package bar
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// fileWidth returns the number of columns of the terminal f refers to, as
+// well as a bool determining whether f is a terminal at all
+func fileWidth(f *os.File) (int, bool) {
+	var size struct {
+		rows, cols, xpixel, ypixel uint16
+	}
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		f.Fd(),
+		uintptr(syscall.TIOCGWINSZ),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if errno != 0 || size.cols == 0 {
+		return 0, false
+	}
+
+	return int(size.cols), true
+}
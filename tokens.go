@@ -5,9 +5,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"math"
-	"os"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type tokens []token
@@ -17,38 +18,160 @@ type token interface {
 	print(*Bar) string
 }
 
+// parameterized is implemented by tokens that accept arguments in
+// parentheses directly following their verb, such as `:bar(40)`
+type parameterized interface {
+	// withArgs returns a copy of the token configured by args, as well as
+	// a bool determining whether the arguments were valid.
+	withArgs(args []string) (token, bool)
+}
+
+// selfColored is implemented by tokens that color their own output with the
+// bar's painter, which paints all of it in the color given by the token's color
+// option (see withOptions) if it has one
+type selfColored interface {
+	token
+	colorsItself()
+}
+
 type tokenFormat struct {
 	stream *bufio.Reader
+	opts   formatOpts
 }
 
 type spaceToken struct{}
-type barToken struct{}
-type percentToken struct{}
-type rateToken struct{}
-type etaToken struct{}
+type tabToken struct{}
+type barToken struct {
+	width int
+}
+type percentToken struct {
+	precision int
+	floor     bool
+}
+type percentOfToken struct {
+	of, precision int
+}
+type rateToken struct {
+	precision int
+	suffix    string
+}
+type smoothedRateToken struct {
+	precision int
+	suffix    string
+}
+type etaToken struct {
+	layout string
+}
+type elapsedToken struct{}
+type countToken struct{}
+type remainingToken struct{}
+type bytesToken struct{}
+type speedToken struct{}
+type spinnerToken struct{}
+type progressToken struct{}
+type totalToken struct{}
+type finishAtToken struct {
+	layout string
+}
+type labelToken struct {
+	width int
+}
+type infoToken struct{}
+type fillToken struct{}
 type customVerbToken struct {
 	verb string
 }
+type coloredToken struct {
+	inner token
+	color Color
+	name  string
+}
 type literalToken struct {
 	content string
 }
 
+// labelVerb is the custom verb whose value is shown by `:label`; it isn't
+// reserved, so that it can be set like any other custom verb.
+const labelVerb = "label"
+
+// labelEllipsis is shown at the end of labels too long for `:label(N)`.
+const labelEllipsis = "…"
+
+// colorOption is the option that colors any verb, given as a `key=value`
+// argument such as `:percent(color=green)`.
+const colorOption = "color"
+
+// infoTokens are the tokens printed by `:info`, separated by spaces.
+var infoTokens = tokens{percentToken{precision: 1}, countToken{}, rateToken{precision: 1}, etaToken{}}
+
+// defaultFinishAtLayout is the time layout used by `:finishat` when none is
+// given in parentheses.
+const defaultFinishAtLayout = "15:04:05"
+
+// formatOpts configures how format strings are tokenized.
+type formatOpts struct {
+	// foldCase matches verbs case-insensitively when true
+	foldCase bool
+	// separators are characters that end a literal or verb, in addition to
+	// the ones that always do (see `readSeparator`)
+	separators string
+	// strict returns an error for unknown verbs and invalid arguments,
+	// rather than printing them as literals
+	strict bool
+}
+
 // tokenize takes a format string and a slice of custom verbs (if any)
-// and returns a slice of tokens that represent the format string.
+// and returns a slice of tokens that represent the format string. It panics
+// if the format string can't be parsed; use ParseFormat to handle the error.
 func tokenize(f string, customVerbs []string) tokens {
+	return mustParseFormat(f, customVerbs, formatOpts{})
+}
+
+// mustParseFormat is like tokenize, but respects the given options.
+func mustParseFormat(f string, customVerbs []string, opts formatOpts) tokens {
+	t, err := parseFormat(strings.NewReader(f), customVerbs, opts)
+	if err != nil {
+		panic(fmt.Sprintf("tokenize: %v", err))
+	}
+
+	return t
+}
+
+// ParseFormat takes a format string and a slice of custom verbs (if any)
+// and returns a slice of tokens that represent the format string, or an
+// error if the format string can't be parsed.
+func ParseFormat(f string, customVerbs []string) (tokens, error) {
+	return parseFormat(strings.NewReader(f), customVerbs, formatOpts{})
+}
+
+// ParseFormatStrict is like ParseFormat, but returns an error naming any
+// unknown verb (such as a typo like `:percnt`) or verb with invalid arguments,
+// rather than printing them as literals.
+func ParseFormatStrict(f string, customVerbs []string) (tokens, error) {
+	return parseFormat(strings.NewReader(f), customVerbs, formatOpts{strict: true})
+}
+
+// parseFormat tokenizes the format read from rd until it is exhausted,
+// returning the first non-EOF error encountered.
+func parseFormat(rd io.Reader, customVerbs []string, opts formatOpts) (tokens, error) {
+	for _, verb := range customVerbs {
+		if isReservedVerb(verb, opts.foldCase) {
+			return nil, fmt.Errorf("custom verb :%s collides with a reserved verb", verb)
+		}
+	}
+
 	var t tokens
 
-	sr := strings.NewReader(f)
-	r := &tokenFormat{bufio.NewReader(sr)}
+	r := &tokenFormat{bufio.NewReader(rd), opts}
 
 	for {
 		tkn, err := r.nextToken(customVerbs)
 		if err != nil {
 			if err == io.EOF {
-				return t
+				return t, nil
 			}
 
-			panic(fmt.Sprintf("tokenize: %v", err))
+			return nil, err
 		}
 
 		t = append(t, tkn)
@@ -68,9 +191,21 @@ func (f *tokenFormat) nextToken(customVerbs []string) (token, error) {
 		switch r {
 		case ' ':
 			return spaceToken{}, nil
+		case '\t':
+			return tabToken{}, nil
 		case ':':
+			if f.readEscapedColon() {
+				return literalToken{":"}, nil
+			}
+
 			return f.readAction(customVerbs)
+		case '"':
+			return f.readQuoted()
 		default:
+			if f.isSeparator(r) {
+				return literalToken{string(r)}, nil
+			}
+
 			return f.readLiteral(r)
 		}
 	}
@@ -78,44 +213,239 @@ func (f *tokenFormat) nextToken(customVerbs []string) (token, error) {
 
 // readAction will consume characters from the input until it finds a valid
 // action verb, returning the corresponding verb token. If no valid verb is
-// found when the input runs out, a literal token will be returned instead.
+// found when the input runs out, a literal token will be returned instead
+// (including for a colon at the very end of the input).
 func (f *tokenFormat) readAction(customVerbs []string) (token, error) {
-	var verb bytes.Buffer
+	var scratch [32]byte
+	verb := scratch[:0]
 
 	for {
 		r, _, err := f.stream.ReadRune()
+		if err == io.EOF && len(verb) == 0 {
+			return literalToken{":"}, nil
+		}
 
 		if err != nil {
 			return nil, err
 		}
 
-		verb.Write([]byte(string([]rune{r})))
+		verb = appendRune(verb, r)
 
-		if t, ok := tokenFromString(verb.String(), customVerbs); ok {
-			return t, nil
+		if _, ok := tokenFromString(string(verb), customVerbs, f.opts.foldCase); ok {
+			v := f.readLongerVerb(string(verb), customVerbs)
+			t, _ := tokenFromString(v, customVerbs, f.opts.foldCase)
+			return f.readArguments(v, t)
 		}
 
 		if f.readSeparator() {
-			if t, ok := tokenFromString(verb.String(), customVerbs); ok {
+			if t, ok := tokenFromString(string(verb), customVerbs, f.opts.foldCase); ok {
 				return t, nil
 			}
 
-			return literalToken{":" + verb.String()}, nil
+			// only text that looks like a verb is an error, so that colons
+			// elsewhere (such as in `: done`) are still allowed
+			if r, _ := utf8.DecodeRune(verb); f.opts.strict && unicode.IsLetter(r) {
+				return nil, fmt.Errorf("unknown verb :%s", verb)
+			}
+
+			return literalToken{":" + string(verb)}, nil
+		}
+	}
+}
+
+// readLongerVerb looks for a longer verb starting with the one that was just
+// read (such as `:percentof` after `:percent`), consuming the rest of it and
+// returning it if found so that the longest verb in the format is matched.
+// Otherwise, verb is returned and nothing is consumed.
+func (f *tokenFormat) readLongerVerb(verb string, customVerbs []string) string {
+	longest := verb
+
+	for _, candidate := range append(reservedVerbs(), customVerbs...) {
+		if len(candidate) <= len(longest) || !f.opts.sameVerb(candidate[:len(verb)], verb) {
+			continue
+		}
+
+		rest, err := f.stream.Peek(len(candidate) - len(verb))
+		if err == nil && f.opts.sameVerb(candidate, verb+string(rest)) {
+			longest = verb + string(rest)
+		}
+	}
+
+	f.stream.Discard(len(longest) - len(verb))
+	return longest
+}
+
+// sameVerb reports whether the verbs a and b are the same, ignoring case if
+// verbs are matched case-insensitively
+func (o formatOpts) sameVerb(a, b string) bool {
+	return a == b || (o.foldCase && strings.EqualFold(a, b))
+}
+
+// readArguments checks whether t accepts arguments and, if so, whether an
+// argument list in parentheses directly follows its verb. When one does, it is
+// consumed and used to configure the returned token. If the argument list is
+// unterminated or invalid, a literal token containing the verb and its
+// arguments is returned instead.
+func (f *tokenFormat) readArguments(verb string, t token) (token, error) {
+	p, ok := t.(parameterized)
+	if !ok {
+		return f.readOptions(t), nil
+	}
+
+	if next, err := f.stream.Peek(1); err != nil || next[0] != byte('(') {
+		return t, nil
+	}
+
+	f.stream.ReadRune()
+
+	var args bytes.Buffer
+
+	for {
+		r, _, err := f.stream.ReadRune()
+		if err == io.EOF {
+			if f.opts.strict {
+				return nil, fmt.Errorf("unterminated arguments for :%s", verb)
+			}
+
+			return literalToken{":" + verb + "(" + args.String()}, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if r == ')' {
+			break
+		}
+
+		args.WriteRune(r)
+	}
+
+	// a token given nothing but options keeps its default arguments
+	positional, options := splitOptions(splitArguments(args.String()))
+	if len(positional) > 0 {
+		t, ok = p.withArgs(positional)
+	}
+
+	if ok {
+		if t, ok := withOptions(t, options); ok {
+			return t, nil
+		}
+	}
+
+	if f.opts.strict {
+		return nil, fmt.Errorf("invalid arguments for :%s: (%s)", verb, args.String())
+	}
+
+	return literalToken{":" + verb + "(" + args.String() + ")"}, nil
+}
+
+// readOptions reads an argument list in parentheses directly following the
+// verb of a token that doesn't accept arguments, as long as it's made up of
+// nothing but valid options (such as `:count(color=red)`), returning the token
+// configured by them. Otherwise, t is returned and nothing is consumed, so
+// that the parentheses are printed as literals.
+func (f *tokenFormat) readOptions(t token) token {
+	args, ok := f.peekArguments()
+	if !ok {
+		return t
+	}
+
+	positional, options := splitOptions(splitArguments(args))
+	if len(positional) > 0 {
+		return t
+	}
+
+	configured, ok := withOptions(t, options)
+	if !ok {
+		return t
+	}
+
+	f.stream.Discard(len(args) + len("()"))
+	return configured
+}
+
+// peekArguments returns the contents of the argument list in parentheses
+// directly following a verb, as well as a bool determining whether there is
+// one, without consuming it
+func (f *tokenFormat) peekArguments() (string, bool) {
+	for n := 1; ; n++ {
+		next, err := f.stream.Peek(n)
+		if err != nil || next[0] != byte('(') {
+			return "", false
+		}
+
+		if n > 1 && next[n-1] == byte(')') {
+			return string(next[1 : n-1]), true
 		}
 	}
 }
 
+// splitOptions separates the `key=value` options that any token accepts
+// (see withOptions) from the rest of a token's arguments.
+func splitOptions(args []string) (positional, options []string) {
+	for _, arg := range args {
+		// like other arguments, options may have spaces around the `=`
+		if i := strings.Index(arg, "="); i >= 0 && strings.TrimSpace(arg[:i]) == colorOption {
+			options = append(options, colorOption+"="+strings.TrimSpace(arg[i+1:]))
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	return positional, options
+}
+
+// withOptions returns t configured by the given `key=value` options, as well
+// as a bool determining whether they were valid. The only option is color,
+// which takes the name of a standard color and prints the token in it.
+func withOptions(t token, options []string) (token, bool) {
+	for _, option := range options {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(option, colorOption+"=")))
+
+		c, ok := colorNames[name]
+		if !ok {
+			return nil, false
+		}
+
+		t = coloredToken{inner: unwrapToken(t), color: c, name: name}
+	}
+
+	return t, true
+}
+
+// unwrapToken returns the token that t prints in color, if it's a colored
+// token, or t itself otherwise
+func unwrapToken(t token) token {
+	if ct, ok := t.(coloredToken); ok {
+		return ct.inner
+	}
+
+	return t
+}
+
+// splitArguments splits a comma-separated argument list, trimming any
+// whitespace surrounding each argument.
+func splitArguments(s string) []string {
+	args := strings.Split(s, ",")
+	for i, arg := range args {
+		args[i] = strings.TrimSpace(arg)
+	}
+
+	return args
+}
+
 // readLiteral will consume characters from the input until it encounters
 // a separator character (see `readSeparator`), returning a literal token
 // containing the characters it consumed.
 func (f *tokenFormat) readLiteral(prefix rune) (token, error) {
-	var value bytes.Buffer
-
-	value.Write([]byte(string([]rune{prefix})))
+	// most literals are short words, which fit in scratch without allocating
+	var scratch [32]byte
+	value := appendRune(scratch[:0], prefix)
 
 	for {
 		if f.readSeparator() {
-			return literalToken{value.String()}, nil
+			return literalToken{string(value)}, nil
 		}
 
 		r, _, err := f.stream.ReadRune()
@@ -124,39 +454,249 @@ func (f *tokenFormat) readLiteral(prefix rune) (token, error) {
 			return nil, err
 		}
 
-		value.Write([]byte(string([]rune{r})))
+		value = appendRune(value, r)
 	}
 }
 
-// readSeparator looks for a separator character (one of ` `, `:`, or *EOF*), returning
-// `true` if one is found and `false` otherwise. It does not consume any characters
-// from the input.
+// appendRune appends the UTF-8 encoding of r to b, appending single-byte
+// (ASCII) runes directly since they make up most formats
+func appendRune(b []byte, r rune) []byte {
+	if r < utf8.RuneSelf {
+		return append(b, byte(r))
+	}
+
+	return append(b, string(r)...)
+}
+
+// readSeparator looks for a separator character (one of ` `, `\t`, `:`, `"`, *EOF*,
+// or any of the format's own separators), returning `true` if one is found and
+// `false` otherwise. It does not consume any characters from the input.
 func (f *tokenFormat) readSeparator() bool {
 	p, err := f.stream.Peek(1)
-	if err != nil || p[0] == byte(' ') || p[0] == byte(':') {
+	if err != nil || p[0] == byte(' ') || p[0] == byte('\t') || p[0] == byte(':') || p[0] == byte('"') {
 		return true
 	}
+
+	if f.opts.separators == "" {
+		return false
+	}
+
+	// a multibyte separator may not be fully buffered yet, which Peek reports
+	// as an error even though the bytes it does return are still usable
+	p, _ = f.stream.Peek(utf8.UTFMax)
+	r, _ := utf8.DecodeRune(p)
+	return f.isSeparator(r)
+}
+
+// isSeparator reports whether r is one of the format's own separators, which
+// are printed as literals on their own
+func (f *tokenFormat) isSeparator(r rune) bool {
+	return strings.ContainsRune(f.opts.separators, r)
+}
+
+// readQuoted will consume characters from the input until it finds the `"`
+// closing the one that was just consumed, returning a literal token containing
+// everything in between (including spaces and colons). Like `::` for a colon,
+// a doubled `""` inside the quotes stands for a literal quote, and so does an
+// empty pair of quotes on its own. If the quote is never closed, it is
+// returned as a literal on its own and whatever followed it is tokenized as
+// usual.
+func (f *tokenFormat) readQuoted() (token, error) {
+	var value, raw bytes.Buffer
+
+	for {
+		r, _, err := f.stream.ReadRune()
+		if err == io.EOF {
+			f.stream = bufio.NewReader(&raw)
+			return literalToken{"\""}, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		raw.WriteRune(r)
+
+		if r == '"' {
+			if p, err := f.stream.Peek(1); err == nil && p[0] == byte('"') {
+				f.stream.ReadRune()
+				raw.WriteRune(r)
+				value.WriteRune(r)
+				continue
+			}
+
+			if raw.Len() == 1 {
+				return literalToken{"\""}, nil
+			}
+
+			return literalToken{value.String()}, nil
+		}
+
+		value.WriteRune(r)
+	}
+}
+
+// readEscapedColon looks for a second `:` directly following the one that was
+// just consumed, consuming it and returning `true` if found. This allows `::`
+// to be used in a format string to print a literal colon.
+func (f *tokenFormat) readEscapedColon() bool {
+	p, err := f.stream.Peek(1)
+	if err != nil || p[0] != byte(':') {
+		return false
+	}
+
+	f.stream.ReadRune()
+	return true
+}
+
+// checkCustomVerbs returns an error if any of the custom verbs used by t
+// isn't defined by ctx. This is checked once when a bar is created rather
+// than each time a custom verb is printed.
+func (t tokens) checkCustomVerbs(ctx Context) error {
+	for _, tkn := range t {
+		if cv, ok := unwrapToken(tkn).(customVerbToken); ok {
+			if _, ok := ctx.lookup(cv.verb); !ok {
+				return fmt.Errorf("custom verb :%s is not defined in the context", cv.verb)
+			}
+		}
+	}
+
+	return nil
+}
+
+// blank reports whether the tokens render nothing but whitespace, no matter
+// the state of the bar
+func (t tokens) blank() bool {
+	for _, tkn := range t {
+		switch tkn := tkn.(type) {
+		case spaceToken, tabToken:
+		case literalToken:
+			if strings.TrimSpace(tkn.content) != "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// fills reports whether t contains any :fill tokens
+func (t tokens) fills() bool {
+	for _, tkn := range t {
+		if isFill(tkn) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usesCustomVerb reports whether t contains the custom verb
+func (t tokens) usesCustomVerb(verb string) bool {
+	for _, tkn := range t {
+		tkn = unwrapToken(tkn)
+		if cv, ok := tkn.(customVerbToken); ok && cv.verb == verb {
+			return true
+		}
+
+		if _, ok := tkn.(labelToken); ok && verb == labelVerb {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reservedVerbs returns the standard verbs recognized by the tokenizer,
+// which may not be used as custom verbs.
+func reservedVerbs() []string {
+	return []string{
+		"bar",
+		"percent",
+		"percentof",
+		"rate",
+		"ratesmoothed",
+		"eta",
+		"elapsed",
+		"count",
+		"remaining",
+		"bytes",
+		"speed",
+		"spinner",
+		"finishat",
+		"progress",
+		"total",
+		"info",
+		"fill",
+	}
+}
+
+// isReservedVerb reports whether verb is one of the standard verbs,
+// ignoring case if foldCase is true.
+func isReservedVerb(verb string, foldCase bool) bool {
+	for _, reserved := range reservedVerbs() {
+		if verb == reserved || (foldCase && strings.EqualFold(verb, reserved)) {
+			return true
+		}
+	}
+
 	return false
 }
 
 // tokenFromString will return the token parsed from s, as well as a
-// bool determining whether a valid token was found.
-func tokenFromString(s string, customVerbs []string) (token, bool) {
+// bool determining whether a valid token was found. If foldCase is true,
+// verbs are matched case-insensitively.
+func tokenFromString(s string, customVerbs []string, foldCase bool) (token, bool) {
+	verb := s
+	if foldCase {
+		verb = strings.ToLower(s)
+	}
+
 	// check for standard verbs
-	switch s {
+	switch verb {
 	case "bar":
 		return barToken{}, true
 	case "percent":
-		return percentToken{}, true
+		return percentToken{precision: 1}, true
+	case "percentof":
+		return percentOfToken{precision: 1}, true
 	case "rate":
-		return rateToken{}, true
+		return rateToken{precision: 1}, true
+	case "ratesmoothed":
+		return smoothedRateToken{precision: 1}, true
 	case "eta":
 		return etaToken{}, true
+	case "elapsed":
+		return elapsedToken{}, true
+	case "count":
+		return countToken{}, true
+	case "remaining":
+		return remainingToken{}, true
+	case "bytes":
+		return bytesToken{}, true
+	case "speed":
+		return speedToken{}, true
+	case "spinner":
+		return spinnerToken{}, true
+	case "finishat":
+		return finishAtToken{layout: defaultFinishAtLayout}, true
+	case "progress":
+		return progressToken{}, true
+	case "total":
+		return totalToken{}, true
+	case "info":
+		return infoToken{}, true
+	case "fill":
+		return fillToken{}, true
+	case labelVerb:
+		return labelToken{}, true
 	}
 
 	// check for custom verbs
 	for _, verb := range customVerbs {
-		if s == verb {
+		if s == verb || (foldCase && strings.EqualFold(s, verb)) {
 			return customVerbToken{verb}, true
 		}
 	}
@@ -164,6 +704,123 @@ func tokenFromString(s string, customVerbs []string) (token, bool) {
 	return nil, false
 }
 
+//
+// argument implementations
+//
+
+func (t barToken) withArgs(args []string) (token, bool) {
+	if len(args) != 1 {
+		return nil, false
+	}
+
+	width, err := strconv.Atoi(args[0])
+	if err != nil || width <= 0 {
+		return nil, false
+	}
+
+	return barToken{width: width}, true
+}
+
+func (t percentToken) withArgs(args []string) (token, bool) {
+	// `floor` may be given on its own for a whole percentage, or after the
+	// precision
+	floor := len(args) > 0 && args[len(args)-1] == "floor"
+	if floor {
+		args = args[:len(args)-1]
+		if len(args) == 0 {
+			return percentToken{precision: 0, floor: true}, true
+		}
+	}
+
+	if len(args) != 1 {
+		return nil, false
+	}
+
+	precision, err := strconv.Atoi(args[0])
+	if err != nil || precision < 0 {
+		return nil, false
+	}
+
+	return percentToken{precision: precision, floor: floor}, true
+}
+
+func (t percentOfToken) withArgs(args []string) (token, bool) {
+	if len(args) > 2 {
+		return nil, false
+	}
+
+	of, err := strconv.Atoi(args[0])
+	if err != nil || of < 0 {
+		return nil, false
+	}
+
+	t = percentOfToken{of: of, precision: 1}
+	if len(args) == 2 {
+		t.precision, err = strconv.Atoi(args[1])
+		if err != nil || t.precision < 0 {
+			return nil, false
+		}
+	}
+
+	return t, true
+}
+
+func (t labelToken) withArgs(args []string) (token, bool) {
+	if len(args) != 1 {
+		return nil, false
+	}
+
+	width, err := strconv.Atoi(args[0])
+	if err != nil || width <= 0 {
+		return nil, false
+	}
+
+	return labelToken{width: width}, true
+}
+
+func (t rateToken) withArgs(args []string) (token, bool) {
+	if len(args) > 2 {
+		return nil, false
+	}
+
+	precision, err := strconv.Atoi(args[0])
+	if err != nil || precision < 0 {
+		return nil, false
+	}
+
+	t = rateToken{precision: precision}
+	if len(args) == 2 {
+		t.suffix = args[1]
+	}
+
+	return t, true
+}
+
+func (t smoothedRateToken) withArgs(args []string) (token, bool) {
+	rt, ok := rateToken{}.withArgs(args)
+	if !ok {
+		return nil, false
+	}
+
+	return smoothedRateToken(rt.(rateToken)), true
+}
+
+func (t etaToken) withArgs(args []string) (token, bool) {
+	if len(args) != 1 || !isDurationLayout(args[0]) {
+		return nil, false
+	}
+
+	return etaToken{layout: args[0]}, true
+}
+
+func (t finishAtToken) withArgs(args []string) (token, bool) {
+	if len(args) != 1 || args[0] == "" {
+		return nil, false
+	}
+
+	return finishAtToken{layout: args[0]}, true
+}
+
 //
 // print implementations
 //
@@ -172,45 +829,430 @@ func (t spaceToken) print(_ *Bar) string {
 	return " "
 }
 
+func (t tabToken) print(_ *Bar) string {
+	return "\t"
+}
+
+func (t barToken) colorsItself()     {}
+func (t percentToken) colorsItself() {}
+
 func (t barToken) print(b *Bar) string {
-	p := int(b.prog() * float64(b.width))
-	return fmt.Sprintf(
-		"%s%s%s%s%s",
-		b.start,
-		strings.Repeat(b.complete, int(math.Max(0, float64(p-1)))),
-		b.head,
-		strings.Repeat(b.incomplete, b.width-p),
-		b.end,
-	)
+	width := b.width
+	if b.fittedWidth > 0 {
+		width = b.fittedWidth
+	}
+
+	if t.width > 0 {
+		width = t.width
+	}
+
+	if b.indeterminate() {
+		return t.bounce(b, width)
+	}
+
+	if b.smooth {
+		return t.smooth(b, width)
+	}
+
+	// the head takes the place of the last completed cell, and is shown
+	// even before any cells have been completed (except once a countdown
+	// has emptied the bar, or when a bar hides its head while empty or full).
+	// A bar of a single cell has no room for both, so it's completed rather
+	// than left showing the head once it's full.
+	p := b.cells(width)
+	head := b.headGlyph()
+	full := b.prog() >= 1
+	if (p == 0 && (b.countdown || b.hideZeroHead)) || ((b.hideFullHead || width == 1) && full) {
+		head = ""
+	}
+
+	complete := p
+	incomplete := width - complete
+	if head != "" {
+		if complete > 0 {
+			complete--
+		}
+		incomplete = width - complete - 1
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(b.start) + complete*len(b.complete) + len(head) + incomplete*len(b.incomplete) + len(b.end))
+
+	pt := t.painter(b, &sb, width)
+	fillColor := b.fillColor()
+	pt.writeText(b.start)
+	if b.direction == RightToLeft {
+		t.paintIncomplete(b, pt, 0, incomplete)
+		t.paintFill(b, pt, fillColor, mirrorHead(head), complete)
+	} else {
+		t.paintFill(b, pt, fillColor, head, complete)
+		t.paintIncomplete(b, pt, width-incomplete, incomplete)
+	}
+	pt.writeText(b.end)
+	pt.reset()
+
+	return sb.String()
+}
+
+// painter returns a painter for the cells of a bar of the given width,
+// which overlays the bar's percentage on its center if it should be shown
+func (t barToken) painter(b *Bar, sb *strings.Builder, width int) *painter {
+	pt := b.painter(sb)
+	if b.percentInBar {
+		pt.overlay = percentOverlay(b, width)
+	}
+
+	return pt
+}
+
+// percentOverlay returns the cells of a bar of the given width that should
+// be replaced to show its percentage, padded by a space on either side
+// so that it stands out from the cells around it. If the percentage doesn't
+// fit, no cells are replaced.
+func percentOverlay(b *Bar, width int) []string {
+	label := []rune(" " + b.percent(0) + " ")
+	if len(label) > width {
+		return nil
+	}
+
+	overlay := make([]string, width)
+	start := (width - len(label)) / 2
+	for i, r := range label {
+		overlay[start+i] = string(r)
+	}
+
+	return overlay
+}
+
+// barCell is a cell of the bar, one column wide; a wide glyph covers the
+// next cell too, which is a wideTail
+type barCell struct {
+	color Color
+	glyph string
+	wide  bool
+}
+
+// paintCells writes n cells of the bar, left to right, getting each from
+// cell. A wide glyph is written along with its tail, which comes before it
+// when the cells are reversed (filling right to left). One without room for
+// its tail, or a tail without its glyph, is written as a space, so that the
+// bar keeps its width.
+func (t barToken) paintCells(pt *painter, n int, reversed bool, cell func(i int) barCell) {
+	for i := 0; i < n; i++ {
+		c := cell(i)
+		if !c.wide && c.glyph != wideTail {
+			pt.write(c.color, c.glyph)
+			continue
+		}
+
+		if i+1 < n {
+			next := cell(i + 1)
+			if !reversed && c.wide && next.glyph == wideTail {
+				pt.writeWide(c.color, c.glyph)
+				i++
+				continue
+			}
+
+			if reversed && c.glyph == wideTail && next.wide {
+				pt.writeWide(next.color, next.glyph)
+				i++
+				continue
+			}
+		}
+
+		pt.write(c.color, " ")
+	}
+}
+
+// paintFill writes the completed cells of the bar and its head (in the
+// reverse order when filling right to left), either in fillColor or along
+// the bar's gradient
+func (t barToken) paintFill(b *Bar, pt *painter, fillColor Color, head string, complete int) {
+	rtl := b.direction == RightToLeft
+
+	n := complete
+	if head != "" {
+		n++
+	}
+
+	t.paintCells(pt, n, rtl, func(i int) barCell {
+		// pos counts cells from where the bar starts filling
+		pos := i
+		if rtl {
+			pos = n - 1 - i
+		}
+
+		c := b.completeCell(pos)
+		if head != "" && pos == n-1 {
+			c = barCell{glyph: head}
+		}
+
+		c.color = fillColor
+		if len(b.gradient) > 0 {
+			c.color = gradientColor(b.gradient, pos, n)
+		}
+
+		return c
+	})
+}
+
+// paintIncomplete writes n incomplete cells of the bar, the first of which
+// is from cells from its left edge
+func (t barToken) paintIncomplete(b *Bar, pt *painter, from, n int) {
+	t.paintCells(pt, n, false, func(i int) barCell {
+		c := b.incompleteCell(from + i)
+		c.color = b.incompleteColor
+		return c
+	})
+}
+
+// partialBlocks are the glyphs used for a cell that is filled by
+// 0/8ths through 7/8ths, respectively
+var partialBlocks = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// smooth renders the bar with full blocks for completed cells and a partial
+// block for the boundary cell, so that it advances in eighths of a cell
+func (t barToken) smooth(b *Bar, width int) string {
+	fill := b.filled() * float64(width)
+	full := int(fill)
+	partial := partialBlocks[int((fill-float64(full))*8)]
+
+	empty := width - full
+	if partial != "" {
+		empty--
+	}
+
+	var sb strings.Builder
+	pt := t.painter(b, &sb, width)
+	fillColor := b.fillColor()
+	pt.writeText(b.start)
+	pt.repeat(fillColor, "█", full)
+	pt.write(fillColor, partial)
+	t.paintIncomplete(b, pt, width-empty, empty)
+	pt.writeText(b.end)
+	pt.reset()
+
+	return sb.String()
+}
+
+// bounce renders a single block that moves back and forth across the bar
+// on each render, used when the total is unknown. The block is the first
+// completed cell, or the first two if it's a wide character.
+func (t barToken) bounce(b *Bar, width int) string {
+	size := 1
+	if b.completeCell(0).wide && width > 1 {
+		size = 2
+	}
+
+	pos := 0
+	if width > size {
+		period := 2 * (width - size)
+		pos = b.frame % period
+		if pos > width-size {
+			pos = period - pos
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(b.start) + (width-1)*len(b.incomplete) + len(b.complete) + len(b.end))
+
+	pt := b.painter(&sb)
+	pt.writeText(b.start)
+	t.paintIncomplete(b, pt, 0, pos)
+	t.paintCells(pt, size, false, func(i int) barCell {
+		c := b.completeCell(i)
+		c.color = b.completeColor
+		return c
+	})
+	t.paintIncomplete(b, pt, pos+size, width-pos-size)
+	pt.writeText(b.end)
+	pt.reset()
+
+	return sb.String()
 }
 
 func (t percentToken) print(b *Bar) string {
-	return fmt.Sprintf("%.1f%%", b.prog()*100)
+	s := "--%"
+	if !b.indeterminate() {
+		if t.floor {
+			s = b.flooredPercent(t.precision)
+		} else {
+			s = b.percent(t.precision)
+		}
+	}
+
+	if b.padPercent {
+		s = padLeft(s, displayWidth(b.formatPercent(100, t.precision)))
+	}
+
+	c := NoColor
+	if !b.indeterminate() && b.colorPercent {
+		c = b.fillColor()
+	}
+
+	var sb strings.Builder
+	pt := b.painter(&sb)
+	pt.write(c, s)
+	pt.reset()
+
+	return sb.String()
+}
+
+func (t percentOfToken) print(b *Bar) string {
+	if t.of == 0 {
+		return "--%"
+	}
+
+	return b.formatPercent(float64(b.progress)/float64(t.of)*100, t.precision)
 }
 
 func (t rateToken) print(b *Bar) string {
-	return fmt.Sprintf("%.1f", b.rate)
+	return fmt.Sprintf("%.*f%s", t.precision, b.rate, t.suffix)
+}
+
+func (t smoothedRateToken) print(b *Bar) string {
+	return fmt.Sprintf("%.*f%s", t.precision, b.smoothedRate(), t.suffix)
+}
+
+// print is empty, since the spaces printed by a fill are worked out from the
+// width of the rest of the line (see `fitTokens`)
+func (t fillToken) print(_ *Bar) string {
+	return ""
+}
+
+func (t infoToken) print(b *Bar) string {
+	parts := make([]string, len(infoTokens))
+	for i, tkn := range infoTokens {
+		parts[i] = tkn.print(b)
+	}
+
+	return strings.Join(parts, " ")
 }
 
 func (t etaToken) print(b *Bar) string {
-	return b.eta.String()
+	if b.etaDone != "" && b.estimateDone() {
+		return b.etaDone
+	}
+
+	eta, ok := b.estimate()
+	if !ok {
+		return "--"
+	}
+
+	if t.layout != "" {
+		return formatDuration(eta, t.layout)
+	}
+
+	return eta.String()
+}
+
+func (t elapsedToken) print(b *Bar) string {
+	return b.elapsed().String()
+}
+
+func (t countToken) print(b *Bar) string {
+	if b.padCount {
+		return fmt.Sprintf("%*d/%d", len(strconv.Itoa(b.total)), b.progress, b.total)
+	}
+
+	return fmt.Sprintf("%d/%d", b.progress, b.total)
+}
+
+func (t progressToken) print(b *Bar) string {
+	return strconv.Itoa(b.progress)
+}
+
+func (t totalToken) print(b *Bar) string {
+	return strconv.Itoa(b.total)
+}
+
+func (t remainingToken) print(b *Bar) string {
+	return fmt.Sprintf("%d", b.remaining())
+}
+
+func (t bytesToken) print(b *Bar) string {
+	if b.countBytes {
+		return formatBytes(float64(b.bytesDone), b.binaryUnits)
+	}
+
+	return formatBytes(float64(b.progress), b.binaryUnits)
+}
+
+func (t speedToken) print(b *Bar) string {
+	if b.countBytes {
+		return formatBytes(b.byteRate, b.binaryUnits) + "/s"
+	}
+
+	return formatBytes(b.rate, b.binaryUnits) + "/s"
+}
+
+func (t spinnerToken) print(b *Bar) string {
+	return b.spinner[b.frame%len(b.spinner)]
+}
+
+func (t finishAtToken) print(b *Bar) string {
+	eta, ok := b.estimate()
+	if !ok {
+		return "--:--:--"
+	}
+
+	return b.now().Add(eta).Format(t.layout)
+}
+
+func (t labelToken) print(b *Bar) string {
+	var label string
+	if def, ok := Context(b.context).lookup(labelVerb); ok {
+		label = def.valueFor(b)
+	}
+
+	if t.width == 0 {
+		return label
+	}
+
+	// truncating may leave the label a column short if it would've split a
+	// wide character, so it's padded afterwards either way
+	label = truncate(label, t.width, labelEllipsis)
+	if pad := t.width - displayWidth(label); pad > 0 {
+		label += strings.Repeat(" ", pad)
+	}
+
+	return label
 }
 
 func (t customVerbToken) print(b *Bar) string {
-	for _, def := range b.context {
-		if def.verb == t.verb {
-			return def.value.String()
-		}
+	if def, ok := Context(b.context).lookup(t.verb); ok {
+		return def.valueFor(b)
 	}
 
-	fmt.Fprintf(os.Stderr, "tokenize: only use `:` for custom verbs")
-	return t.verb
+	// the format and context are checked against each other when the bar is
+	// created, so this is only reachable if they're changed independently
+	return ":" + t.verb
 }
 
 func (t literalToken) print(_ *Bar) string {
 	return t.content
 }
 
+func (t coloredToken) print(b *Bar) string {
+	// tokens that color themselves are colored entirely in the token's color
+	// instead, rather than having their own colors nested inside it
+	if _, ok := t.inner.(selfColored); ok {
+		b.tokenColor = t.color
+		defer func() { b.tokenColor = NoColor }()
+
+		return t.inner.print(b)
+	}
+
+	s := t.inner.print(b)
+
+	var sb strings.Builder
+	pt := b.painter(&sb)
+	pt.write(t.color, s)
+	pt.reset()
+
+	return sb.String()
+}
+
 //
 // debug implementations
 //
@@ -219,6 +1261,10 @@ func (t spaceToken) debug(b *Bar) string {
 	return " "
 }
 
+func (t tabToken) debug(b *Bar) string {
+	return "\t"
+}
+
 func (t barToken) debug(b *Bar) string {
 	return fmt.Sprintf("<barToken p={%d} t={%d}>", b.progress, b.total)
 }
@@ -227,14 +1273,70 @@ func (t percentToken) debug(b *Bar) string {
 	return fmt.Sprintf("<percentToken \"%s\">", t.print(b))
 }
 
+func (t percentOfToken) debug(b *Bar) string {
+	return fmt.Sprintf("<percentOfToken of={%d} \"%s\">", t.of, t.print(b))
+}
+
 func (t rateToken) debug(b *Bar) string {
 	return fmt.Sprintf("<rateToken \"%s\">", t.print(b))
 }
 
+func (t smoothedRateToken) debug(b *Bar) string {
+	return fmt.Sprintf("<smoothedRateToken \"%s\">", t.print(b))
+}
+
+func (t fillToken) debug(_ *Bar) string {
+	return "<fillToken>"
+}
+
+func (t infoToken) debug(b *Bar) string {
+	return fmt.Sprintf("<infoToken \"%s\">", t.print(b))
+}
+
 func (t etaToken) debug(b *Bar) string {
 	return fmt.Sprintf("<etaToken \"%s\">", t.print(b))
 }
 
+func (t elapsedToken) debug(b *Bar) string {
+	return fmt.Sprintf("<elapsedToken \"%s\">", t.print(b))
+}
+
+func (t countToken) debug(b *Bar) string {
+	return fmt.Sprintf("<countToken p={%d} t={%d}>", b.progress, b.total)
+}
+
+func (t progressToken) debug(b *Bar) string {
+	return fmt.Sprintf("<progressToken p={%d}>", b.progress)
+}
+
+func (t totalToken) debug(b *Bar) string {
+	return fmt.Sprintf("<totalToken t={%d}>", b.total)
+}
+
+func (t remainingToken) debug(b *Bar) string {
+	return fmt.Sprintf("<remainingToken \"%s\">", t.print(b))
+}
+
+func (t bytesToken) debug(b *Bar) string {
+	return fmt.Sprintf("<bytesToken p={%d} \"%s\">", b.progress, t.print(b))
+}
+
+func (t speedToken) debug(b *Bar) string {
+	return fmt.Sprintf("<speedToken \"%s\">", t.print(b))
+}
+
+func (t spinnerToken) debug(b *Bar) string {
+	return fmt.Sprintf("<spinnerToken frame={%d} \"%s\">", b.frame%len(b.spinner), t.print(b))
+}
+
+func (t finishAtToken) debug(b *Bar) string {
+	return fmt.Sprintf("<finishAtToken layout={%s} \"%s\">", t.layout, t.print(b))
+}
+
+func (t labelToken) debug(b *Bar) string {
+	return fmt.Sprintf("<labelToken width={%d} \"%s\">", t.width, t.print(b))
+}
+
 func (t customVerbToken) debug(b *Bar) string {
 	return fmt.Sprintf("<customVerbToken verb=\"%s\" value=\"%s\">", t.verb, t.print(b))
 }
@@ -242,3 +1344,7 @@ func (t customVerbToken) debug(b *Bar) string {
 func (t literalToken) debug(b *Bar) string {
 	return fmt.Sprintf("<literalToken \"%s\">", t.content)
 }
+
+func (t coloredToken) debug(b *Bar) string {
+	return fmt.Sprintf("<coloredToken color=\"%s\" %s>", t.name, t.inner.debug(b))
+}
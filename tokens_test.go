@@ -1,10 +1,30 @@
 package bar
 
 import (
+	"bufio"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
+// failingReader yields its content and then fails with err instead of io.EOF
+type failingReader struct {
+	content string
+	err     error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.content) == 0 {
+		return 0, r.err
+	}
+
+	n := copy(p, r.content)
+	r.content = r.content[n:]
+	return n, nil
+}
+
 func TestTokenize(t *testing.T) {
 	var testCases = []struct {
 		formatString string
@@ -12,16 +32,32 @@ func TestTokenize(t *testing.T) {
 	}{
 		{"", nil},
 		{" ", tokens{spaceToken{}}},
+		{"   ", tokens{spaceToken{}, spaceToken{}, spaceToken{}}},
 		{":bar", tokens{barToken{}}},
 		{" :bar", tokens{spaceToken{}, barToken{}}},
 		{" :bar ", tokens{spaceToken{}, barToken{}, spaceToken{}}},
 		{"  :bar", tokens{spaceToken{}, spaceToken{}, barToken{}}},
 		{":bar:bar", tokens{barToken{}, barToken{}}},
-		{":bar:rate", tokens{barToken{}, rateToken{}}},
+		{":bar:rate", tokens{barToken{}, rateToken{precision: 1}}},
+		{":elapsed", tokens{elapsedToken{}}},
+		{":eta:elapsed", tokens{etaToken{}, elapsedToken{}}},
+		{":count", tokens{countToken{}}},
+		{":bar :count", tokens{barToken{}, spaceToken{}, countToken{}}},
+		{":remaining left", tokens{remainingToken{}, spaceToken{}, literalToken{"left"}}},
+		{":bytes", tokens{bytesToken{}}},
+		{":bytes :speed", tokens{bytesToken{}, spaceToken{}, speedToken{}}},
+		{":spinner loading", tokens{spinnerToken{}, spaceToken{}, literalToken{"loading"}}},
+		{":progress of :total", tokens{progressToken{}, spaceToken{}, literalToken{"of"}, spaceToken{}, totalToken{}}},
+		{":percent:progress", tokens{percentToken{precision: 1}, progressToken{}}},
 		{"bar", tokens{literalToken{"bar"}}},
 		{"bar:bar", tokens{literalToken{"bar"}, barToken{}}},
 		{"不与", tokens{literalToken{"不与"}}},
 		{"不与:bar", tokens{literalToken{"不与"}, barToken{}}},
+		{"\t", tokens{tabToken{}}},
+		{":bar\t:percent", tokens{barToken{}, tabToken{}, percentToken{precision: 1}}},
+		{"label\t:bar", tokens{literalToken{"label"}, tabToken{}, barToken{}}},
+		{"label\t\t :bar", tokens{literalToken{"label"}, tabToken{}, tabToken{}, spaceToken{}, barToken{}}},
+		{":unknown\t:bar", tokens{literalToken{":unknown"}, tabToken{}, barToken{}}},
 	}
 
 	for i, testCase := range testCases {
@@ -71,6 +107,10 @@ func TestTokenizeWithCustomVerbs(t *testing.T) {
 		{":custom", nil, tokens{literalToken{":custom"}}},
 		{":custom", []string{"custom"}, tokens{customVerbToken{"custom"}}},
 		{":bar:custom", []string{"custom"}, tokens{barToken{}, customVerbToken{"custom"}}},
+		{":totals", []string{"totals"}, tokens{customVerbToken{"totals"}}},
+		{":total :totals", []string{"totals"}, tokens{totalToken{}, spaceToken{}, customVerbToken{"totals"}}},
+		{":file :files", []string{"file", "files"}, tokens{customVerbToken{"file"}, spaceToken{}, customVerbToken{"files"}}},
+		{":files", []string{"files", "file"}, tokens{customVerbToken{"files"}}},
 	}
 
 	for i, testCase := range testCases {
@@ -87,3 +127,530 @@ func TestTokenizeWithCustomVerbs(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenizeWithEscapedColons(t *testing.T) {
+	var testCases = []struct {
+		formatString string
+		expected     tokens
+	}{
+		{"::", tokens{literalToken{":"}}},
+		{"::bar", tokens{literalToken{":"}, literalToken{"bar"}}},
+		{":::bar", tokens{literalToken{":"}, barToken{}}},
+		{"time::bar", tokens{literalToken{"time"}, literalToken{":"}, literalToken{"bar"}}},
+		{"time:: :bar", tokens{literalToken{"time"}, literalToken{":"}, spaceToken{}, barToken{}}},
+		{":bar::", tokens{barToken{}, literalToken{":"}}},
+		{":bar ::::", tokens{barToken{}, spaceToken{}, literalToken{":"}, literalToken{":"}}},
+	}
+
+	for i, testCase := range testCases {
+		got := tokenize(testCase.formatString, nil)
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf(
+				"[%d] tokenize(%#v, nil)\n\n  got %#v\n  want %#v",
+				i,
+				testCase.formatString,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+}
+
+func TestTokenizeWithTrailingColons(t *testing.T) {
+	var testCases = []struct {
+		formatString string
+		expected     tokens
+	}{
+		{":", tokens{literalToken{":"}}},
+		{"progress:", tokens{literalToken{"progress"}, literalToken{":"}}},
+		{":bar:", tokens{barToken{}, literalToken{":"}}},
+		{":bar :", tokens{barToken{}, spaceToken{}, literalToken{":"}}},
+		{"::", tokens{literalToken{":"}}},
+		{":::", tokens{literalToken{":"}, literalToken{":"}}},
+		{":unknownverb", tokens{literalToken{":unknownverb"}}},
+		{":bar:unknownverb", tokens{barToken{}, literalToken{":unknownverb"}}},
+	}
+
+	for i, testCase := range testCases {
+		got := tokenize(testCase.formatString, nil)
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf(
+				"[%d] tokenize(%#v, nil)\n\n  got %#v\n  want %#v",
+				i,
+				testCase.formatString,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+}
+
+func TestTokenizeWithQuotes(t *testing.T) {
+	var testCases = []struct {
+		formatString string
+		expected     tokens
+	}{
+		{`"my label":bar`, tokens{literalToken{"my label"}, barToken{}}},
+		{`"my label" :bar`, tokens{literalToken{"my label"}, spaceToken{}, barToken{}}},
+		{`:bar "  two  spaces  "`, tokens{barToken{}, spaceToken{}, literalToken{"  two  spaces  "}}},
+		{`"eta: ":eta`, tokens{literalToken{"eta: "}, etaToken{}}},
+		{`size"a b"`, tokens{literalToken{"size"}, literalToken{"a b"}}},
+		{`""`, tokens{literalToken{`"`}}},
+		{`say "hi" :bar`, tokens{literalToken{"say"}, spaceToken{}, literalToken{"hi"}, spaceToken{}, barToken{}}},
+		{`say """hi""" :bar`, tokens{literalToken{"say"}, spaceToken{}, literalToken{`"hi"`}, spaceToken{}, barToken{}}},
+		{`"a ""b"" c"`, tokens{literalToken{`a "b" c`}}},
+		{`""""`, tokens{literalToken{`"`}}},
+		{`5"" :bar`, tokens{literalToken{"5"}, literalToken{`"`}, spaceToken{}, barToken{}}},
+		{`"a""b`, tokens{literalToken{`"`}, literalToken{"a"}, literalToken{`"`}, literalToken{"b"}}},
+		{`"`, tokens{literalToken{`"`}}},
+		{`5" :bar`, tokens{literalToken{"5"}, literalToken{`"`}, spaceToken{}, barToken{}}},
+		{`"unterminated :bar`, tokens{literalToken{`"`}, literalToken{"unterminated"}, spaceToken{}, barToken{}}},
+	}
+
+	for i, testCase := range testCases {
+		got := tokenize(testCase.formatString, nil)
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf(
+				"[%d] tokenize(%#v, nil)\n\n  got %#v\n  want %#v",
+				i,
+				testCase.formatString,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	got, err := ParseFormat(" :bar :rate", nil)
+	if err != nil {
+		t.Fatalf("ParseFormat returned unexpected error: %v", err)
+	}
+
+	expected := tokens{spaceToken{}, barToken{}, spaceToken{}, rateToken{precision: 1}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseFormat(\" :bar :rate\", nil)\n\n  got %#v\n  want %#v", got, expected)
+	}
+}
+
+func TestParseFormatPropagatesReadErrors(t *testing.T) {
+	readErr := errors.New("read failed")
+
+	for i, content := range []string{"", " :bar", " :ba", "loading"} {
+		got, err := parseFormat(&failingReader{content, readErr}, nil, formatOpts{})
+		if err != readErr {
+			t.Errorf("[%d] parseFormat(%#v)\n\n  got error %v\n  want %v", i, content, err, readErr)
+		}
+
+		if got != nil {
+			t.Errorf("[%d] parseFormat(%#v)\n\n  got %#v\n  want nil", i, content, got)
+		}
+	}
+}
+
+func TestTokenizeWithArguments(t *testing.T) {
+	var testCases = []struct {
+		formatString string
+		expected     tokens
+	}{
+		{":bar(40)", tokens{barToken{width: 40}}},
+		{":bar(40) :bar(8)", tokens{barToken{width: 40}, spaceToken{}, barToken{width: 8}}},
+		{"(:bar(5))", tokens{literalToken{"("}, barToken{width: 5}, literalToken{")"}}},
+		{":bar (40)", tokens{barToken{}, spaceToken{}, literalToken{"(40)"}}},
+		{":bar(", tokens{literalToken{":bar("}}},
+		{":bar(40", tokens{literalToken{":bar(40"}}},
+		{":bar()", tokens{literalToken{":bar()"}}},
+		{":bar(abc)", tokens{literalToken{":bar(abc)"}}},
+		{":bar(0)", tokens{literalToken{":bar(0)"}}},
+		{":bar(-3)", tokens{literalToken{":bar(-3)"}}},
+		{":bar(1,2)", tokens{literalToken{":bar(1,2)"}}},
+		{":bar(abc) :rate", tokens{literalToken{":bar(abc)"}, spaceToken{}, rateToken{precision: 1}}},
+		{":percent", tokens{percentToken{precision: 1}}},
+		{":percent(0)", tokens{percentToken{precision: 0}}},
+		{":percent(3)", tokens{percentToken{precision: 3}}},
+		{":percent(-1)", tokens{literalToken{":percent(-1)"}}},
+		{":percent(x)", tokens{literalToken{":percent(x)"}}},
+		{":percent( 2 )", tokens{percentToken{precision: 2}}},
+		{":percent(floor)", tokens{percentToken{precision: 0, floor: true}}},
+		{":percent(1, floor)", tokens{percentToken{precision: 1, floor: true}}},
+		{":percent(floor,1)", tokens{literalToken{":percent(floor,1)"}}},
+		{":percent(floor,floor)", tokens{literalToken{":percent(floor,floor)"}}},
+		{":percent(floor, color=green)", tokens{coloredToken{inner: percentToken{precision: 0, floor: true}, color: Green, name: "green"}}},
+		{":percentof", tokens{percentOfToken{precision: 1}}},
+		{":percentof(500)", tokens{percentOfToken{of: 500, precision: 1}}},
+		{":percentof(500, 0)", tokens{percentOfToken{of: 500, precision: 0}}},
+		{":percentof(0)", tokens{percentOfToken{precision: 1}}},
+		{":percentof(-5)", tokens{literalToken{":percentof(-5)"}}},
+		{":percentof(x)", tokens{literalToken{":percentof(x)"}}},
+		{":percentof(5,-1)", tokens{literalToken{":percentof(5,-1)"}}},
+		{":percent:percentof(9)", tokens{percentToken{precision: 1}, percentOfToken{of: 9, precision: 1}}},
+		{":percento", tokens{percentToken{precision: 1}, literalToken{"o"}}},
+		{":rate", tokens{rateToken{precision: 1}}},
+		{":rate(2)", tokens{rateToken{precision: 2}}},
+		{":rate(0,ops)", tokens{rateToken{precision: 0, suffix: "ops"}}},
+		{":rate( 2 , ops/s )", tokens{rateToken{precision: 2, suffix: "ops/s"}}},
+		{":rate(ops)", tokens{literalToken{":rate(ops)"}}},
+		{":rate(2,ops,s)", tokens{literalToken{":rate(2,ops,s)"}}},
+		{":ratesmoothed", tokens{smoothedRateToken{precision: 1}}},
+		{":ratesmoothed(0,/s)", tokens{smoothedRateToken{precision: 0, suffix: "/s"}}},
+		{":rate:ratesmoothed", tokens{rateToken{precision: 1}, smoothedRateToken{precision: 1}}},
+		{":ratesmoothed(x)", tokens{literalToken{":ratesmoothed(x)"}}},
+		{":bar( 12 )", tokens{barToken{width: 12}}},
+		{":count(2)", tokens{countToken{}, literalToken{"(2)"}}},
+		{":eta", tokens{etaToken{}}},
+		{":eta(mm:ss)", tokens{etaToken{layout: "mm:ss"}}},
+		{":eta(hh:mm:ss) :bar", tokens{etaToken{layout: "hh:mm:ss"}, spaceToken{}, barToken{}}},
+		{":eta(soon)", tokens{literalToken{":eta(soon)"}}},
+		{":eta(mm,ss)", tokens{literalToken{":eta(mm,ss)"}}},
+		{":finishat", tokens{finishAtToken{layout: "15:04:05"}}},
+		{":finishat(15:04)", tokens{finishAtToken{layout: "15:04"}}},
+		{":finishat()", tokens{literalToken{":finishat()"}}},
+		{":progress(2)", tokens{progressToken{}, literalToken{"(2)"}}},
+		{":total(2)", tokens{totalToken{}, literalToken{"(2)"}}},
+		{":info", tokens{infoToken{}}},
+		{":info(2)", tokens{infoToken{}, literalToken{"(2)"}}},
+		{":label", tokens{labelToken{}}},
+		{":label(12)", tokens{labelToken{width: 12}}},
+		{":label(0)", tokens{literalToken{":label(0)"}}},
+		{":label(wide)", tokens{literalToken{":label(wide)"}}},
+	}
+
+	for i, testCase := range testCases {
+		got := tokenize(testCase.formatString, nil)
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf(
+				"[%d] tokenize(%#v, nil)\n\n  got %#v\n  want %#v",
+				i,
+				testCase.formatString,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+}
+
+func TestTokenizeWithColorOptions(t *testing.T) {
+	green := func(t token) coloredToken {
+		return coloredToken{inner: t, color: Green, name: "green"}
+	}
+
+	var testCases = []struct {
+		formatString string
+		expected     tokens
+	}{
+		{":percent(color=green)", tokens{green(percentToken{precision: 1})}},
+		{":percent(0, color=green)", tokens{green(percentToken{precision: 0})}},
+		{":percent(color=green,0)", tokens{green(percentToken{precision: 0})}},
+		{":percent(color = green)", tokens{green(percentToken{precision: 1})}},
+		{":count( color=green )", tokens{green(countToken{})}},
+		{":percent(color=GREEN)", tokens{green(percentToken{precision: 1})}},
+		{":rate(2,ops,color=green)", tokens{green(rateToken{precision: 2, suffix: "ops"})}},
+		{":bar(10,color=green) :eta", tokens{green(barToken{width: 10}), spaceToken{}, etaToken{}}},
+		{":percent(color=red,color=green)", tokens{green(percentToken{precision: 1})}},
+		{":percent(color=teal)", tokens{literalToken{":percent(color=teal)"}}},
+		{":percent(x,color=green)", tokens{literalToken{":percent(x,color=green)"}}},
+		{":count(color=green)", tokens{green(countToken{})}},
+		{":count(color=green) :total", tokens{green(countToken{}), spaceToken{}, totalToken{}}},
+		{":count(color=teal)", tokens{countToken{}, literalToken{"(color=teal)"}}},
+		{":count(2,color=green)", tokens{countToken{}, literalToken{"(2,color=green)"}}},
+		{":count(color=green", tokens{countToken{}, literalToken{"(color=green"}}},
+		{":count()", tokens{countToken{}, literalToken{"()"}}},
+		{":hello(color=green)", tokens{green(customVerbToken{verb: "hello"})}},
+	}
+
+	for i, testCase := range testCases {
+		got := tokenize(testCase.formatString, []string{"hello"})
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf(
+				"[%d] tokenize(%#v, []string{\"hello\"})\n\n  got %#v\n  want %#v",
+				i,
+				testCase.formatString,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+
+	if _, err := ParseFormatStrict(":percent(color=teal)", nil); err == nil {
+		t.Errorf("ParseFormatStrict with an unknown color returned no error")
+	}
+}
+
+func TestTokenizeWithSeparators(t *testing.T) {
+	var testCases = []struct {
+		formatString string
+		separators   string
+		expected     tokens
+	}{
+		{"done|:bar", "", tokens{literalToken{"done|"}, barToken{}}},
+		{"done|:bar", "|", tokens{literalToken{"done"}, literalToken{"|"}, barToken{}}},
+		{":bar|:percent", "|", tokens{barToken{}, literalToken{"|"}, percentToken{precision: 1}}},
+		{"a|b/c", "|/", tokens{literalToken{"a"}, literalToken{"|"}, literalToken{"b"}, literalToken{"/"}, literalToken{"c"}}},
+		{"||", "|", tokens{literalToken{"|"}, literalToken{"|"}}},
+		{":nope|:bar", "|", tokens{literalToken{":nope"}, literalToken{"|"}, barToken{}}},
+		{"step│:count", "│", tokens{literalToken{"step"}, literalToken{"│"}, countToken{}}},
+		{`"a|b"|`, "|", tokens{literalToken{"a|b"}, literalToken{"|"}}},
+	}
+
+	for i, testCase := range testCases {
+		got := mustParseFormat(testCase.formatString, nil, formatOpts{separators: testCase.separators})
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf(
+				"[%d] tokenize(%#v) with separators %#v\n\n  got %#v\n  want %#v",
+				i,
+				testCase.formatString,
+				testCase.separators,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+
+	b := newTestBar(newFakeClock(), WithDimensions(10, 4), WithFormat("done|:count"), WithSeparators("|"))
+	if got, want := b.format, (tokens{literalToken{"done"}, literalToken{"|"}, countToken{}}); !reflect.DeepEqual(tokens(got), want) {
+		t.Errorf("format of a bar WithSeparators\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestReservedVerbsAreRecognized(t *testing.T) {
+	for _, verb := range reservedVerbs() {
+		if _, ok := tokenFromString(verb, nil, false); !ok {
+			t.Errorf("reserved verb %#v is not recognized by tokenFromString", verb)
+		}
+	}
+}
+
+func TestParseFormatRejectsReservedCustomVerbs(t *testing.T) {
+	var testCases = []struct {
+		customVerbs []string
+		offending   string
+	}{
+		{[]string{"bar"}, ":bar"},
+		{[]string{"custom", "rate"}, ":rate"},
+		{[]string{"spinner", "custom"}, ":spinner"},
+	}
+
+	for i, testCase := range testCases {
+		_, err := ParseFormat(":bar :rate :custom", testCase.customVerbs)
+		if err == nil {
+			t.Errorf("[%d] ParseFormat(_, %#v) returned no error", i, testCase.customVerbs)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), testCase.offending) {
+			t.Errorf("[%d] ParseFormat(_, %#v)\n\n  got error %q\n  want it to name %s", i, testCase.customVerbs, err, testCase.offending)
+		}
+	}
+}
+
+func TestParseFormatStrict(t *testing.T) {
+	var testCases = []struct {
+		formatString string
+		offending    string
+	}{
+		{":bar :percnt", ":percnt"},
+		{":bar :Percent", ":Percent"},
+		{"done :nope", ":nope"},
+		{":percent(abc)", ":percent: (abc)"},
+		{":percent(1", ":percent"},
+	}
+
+	for i, testCase := range testCases {
+		if _, err := ParseFormat(testCase.formatString, nil); err != nil {
+			t.Errorf("[%d] ParseFormat(%#v) returned an error: %v", i, testCase.formatString, err)
+		}
+
+		_, err := ParseFormatStrict(testCase.formatString, nil)
+		if err == nil {
+			t.Errorf("[%d] ParseFormatStrict(%#v) returned no error", i, testCase.formatString)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), testCase.offending) {
+			t.Errorf("[%d] ParseFormatStrict(%#v)\n\n  got error %q\n  want it to name %s", i, testCase.formatString, err, testCase.offending)
+		}
+	}
+
+	// known verbs, custom verbs, escaped colons and colons outside of verbs
+	// are still allowed
+	for _, f := range []string{":bar :percent(1) :custom", "a::b", "ratio: :percent", "time :3", "trailing:"} {
+		strict, err := ParseFormatStrict(f, []string{"custom"})
+		if err != nil {
+			t.Errorf("ParseFormatStrict(%#v) returned an error: %v", f, err)
+			continue
+		}
+
+		lenient, _ := ParseFormat(f, []string{"custom"})
+		if !reflect.DeepEqual(strict, lenient) {
+			t.Errorf("ParseFormatStrict(%#v)\n\n  got %#v\n  want %#v", f, strict, lenient)
+		}
+	}
+}
+
+func TestStrictVerbs(t *testing.T) {
+	if _, err := TryNewWithOpts(WithFormat(":bar :percnt")); err != nil {
+		t.Errorf("TryNewWithOpts with an unknown verb returned an error: %v", err)
+	}
+
+	if _, err := TryNewWithOpts(WithFormat(":bar(wide)")); err != nil {
+		t.Errorf("TryNewWithOpts with invalid arguments returned an error: %v", err)
+	}
+
+	if _, err := TryNewWithOpts(WithFormat(":bar(wide)"), WithStrictVerbs()); err == nil {
+		t.Error("TryNewWithOpts with invalid arguments and WithStrictVerbs returned no error")
+	}
+
+	if _, err := TryNewWithOpts(WithFormat(":percentof :percentof(0)"), WithStrictVerbs()); err != nil {
+		t.Errorf("TryNewWithOpts with :percentof of zero and WithStrictVerbs returned an error: %v", err)
+	}
+
+	if _, err := TryNewWithOpts(WithFormat(":percentof(-5)"), WithStrictVerbs()); err == nil {
+		t.Error("TryNewWithOpts with a negative :percentof and WithStrictVerbs returned no error")
+	}
+
+	_, err := TryNewWithOpts(WithFormat(":bar :percnt"), WithStrictVerbs())
+	if err == nil || !strings.Contains(err.Error(), ":percnt") {
+		t.Errorf("TryNewWithOpts with an unknown verb and WithStrictVerbs\n\n  got error %v\n  want it to name :percnt", err)
+	}
+
+	if _, err := TryNewWithOpts(WithFormat(":BAR :Percent"), WithStrictVerbs(), WithCaseInsensitiveVerbs()); err != nil {
+		t.Errorf("TryNewWithOpts with WithStrictVerbs and WithCaseInsensitiveVerbs returned an error: %v", err)
+	}
+}
+
+func TestTokenizeCaseInsensitive(t *testing.T) {
+	var testCases = []struct {
+		formatString        string
+		customVerbs         []string
+		sensitive, foldCase tokens
+	}{
+		{
+			":Bar",
+			nil,
+			tokens{literalToken{":Bar"}},
+			tokens{barToken{}},
+		},
+		{
+			":PERCENT",
+			nil,
+			tokens{literalToken{":PERCENT"}},
+			tokens{percentToken{precision: 1}},
+		},
+		{
+			"Loading :bar",
+			nil,
+			tokens{literalToken{"Loading"}, spaceToken{}, barToken{}},
+			tokens{literalToken{"Loading"}, spaceToken{}, barToken{}},
+		},
+		{
+			":MyVerb",
+			[]string{"myverb"},
+			tokens{literalToken{":MyVerb"}},
+			tokens{customVerbToken{"myverb"}},
+		},
+		{
+			":myverb",
+			[]string{"myverb"},
+			tokens{customVerbToken{"myverb"}},
+			tokens{customVerbToken{"myverb"}},
+		},
+	}
+
+	for i, testCase := range testCases {
+		for _, foldCase := range []bool{false, true} {
+			expected := testCase.sensitive
+			if foldCase {
+				expected = testCase.foldCase
+			}
+
+			got := mustParseFormat(testCase.formatString, testCase.customVerbs, formatOpts{foldCase: foldCase})
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf(
+					"[%d] mustParseFormat(%#v, %#v, {foldCase: %v})\n\n  got %#v\n  want %#v",
+					i,
+					testCase.formatString,
+					testCase.customVerbs,
+					foldCase,
+					got,
+					expected,
+				)
+			}
+		}
+	}
+}
+
+func TestParseFormatRejectsReservedCustomVerbsIgnoringCase(t *testing.T) {
+	if _, err := parseFormat(strings.NewReader(":Rate"), []string{"Rate"}, formatOpts{}); err != nil {
+		t.Errorf("case-sensitive parseFormat rejected custom verb :Rate: %v", err)
+	}
+
+	if _, err := parseFormat(strings.NewReader(":Rate"), []string{"Rate"}, formatOpts{foldCase: true}); err == nil {
+		t.Error("case-insensitive parseFormat accepted custom verb :Rate")
+	}
+}
+
+func TestTokensBlank(t *testing.T) {
+	var testCases = []struct {
+		formatString string
+		expected     bool
+	}{
+		{"", true},
+		{" ", true},
+		{"   ", true},
+		{" \t ", true},
+		{`" "`, true},
+		{"x", false},
+		{" :bar ", false},
+		{":spinner", false},
+	}
+
+	for i, testCase := range testCases {
+		if got := tokenize(testCase.formatString, nil).blank(); got != testCase.expected {
+			t.Errorf("[%d] tokens(%#v).blank()\n\n  got %v\n  want %v", i, testCase.formatString, got, testCase.expected)
+		}
+	}
+}
+
+func TestReadLiteralAllocations(t *testing.T) {
+	rd := strings.NewReader("")
+	f := &tokenFormat{stream: bufio.NewReader(rd)}
+
+	// the characters of a short literal are collected without allocating,
+	// leaving only the literal's string and the token that holds it
+	for _, literal := range []string{"downloading", "下载中的文件"} {
+		prefix, size := utf8.DecodeRuneInString(literal)
+		rest := literal[size:] + " :bar"
+
+		allocs := testing.AllocsPerRun(100, func() {
+			rd.Reset(rest)
+			f.stream.Reset(rd)
+			f.readLiteral(prefix)
+		})
+
+		if allocs > 2 {
+			t.Errorf("allocations reading the literal %#v\n\n  got %v\n  want at most 2", literal, allocs)
+		}
+	}
+}
+
+func BenchmarkTokenizeASCII(b *testing.B) {
+	format := strings.Repeat("downloading :bar :percent (:count) ", 20)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = tokenize(format, nil)
+	}
+}
+
+func BenchmarkTokenizeMultibyte(b *testing.B) {
+	format := strings.Repeat("下载中 :bar(30) 进度 :percent ", 20)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = tokenize(format, nil)
+	}
+}
@@ -0,0 +1,57 @@
+package bar
+
+import "context"
+
+// Watch stops the bar once ctx is done, clearing it from the terminal rather
+// than leaving a stale bar behind after an operation is cancelled. A bar that
+// has been stopped this way is closed, so any later updates are ignored, but
+// unlike Finish, the bar isn't drawn again and its callback isn't called. If
+// the bar is finished first, ctx is no longer watched.
+//
+// ctx is watched from a goroutine that only exits once ctx is done or the bar
+// is finished, so a bar that's no longer needed should be finished (or ctx
+// cancelled) rather than dropped, or the goroutine leaks.
+func (b *Bar) Watch(ctx context.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	done := b.doneChan()
+	go func() {
+		select {
+		case <-ctx.Done():
+			b.cancel()
+		case <-done:
+		}
+	}()
+}
+
+// cancel closes the bar without finishing it, clearing its line
+func (b *Bar) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	if b.tty && b.group == nil && b.mode == ModeBar {
+		b.output.ClearLine()
+	}
+
+	b.cancelled = true
+	b.close()
+}
+
+// doneChan returns a channel that's closed once the bar is, for goroutines
+// that stop along with the bar; the caller must hold b.mu
+func (b *Bar) doneChan() chan struct{} {
+	if b.done == nil {
+		b.done = make(chan struct{})
+	}
+
+	return b.done
+}
@@ -0,0 +1,77 @@
+package bar
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+// waitUntilClosed waits for a bar being closed in the background, failing the
+// test if it's taking too long
+func waitUntilClosed(t *testing.T, b *Bar) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		closed := b.closed
+		b.mu.Unlock()
+
+		if closed {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatal("bar wasn't closed in time")
+}
+
+func TestWatch(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(10, 4), WithFormat(":count"), WithWriter(&buf), WithTTY(true))
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Watch(ctx)
+
+	b.Tick()
+	cancel()
+	waitUntilClosed(t, b)
+
+	b.Tick()
+	b.Done()
+
+	if got, want := buf.String(), clearLine+"1/10"+clearLine; got != want {
+		t.Errorf("output of a cancelled bar\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	b.Reset()
+	b.Tick()
+
+	if got, want := buf.String(), clearLine+"1/10"+clearLine+clearLine+"1/10"; got != want {
+		t.Errorf("output of a cancelled bar after Reset\n\n  got %#v\n  want %#v", got, want)
+	}
+}
+
+func TestWatchStopsWhenFinished(t *testing.T) {
+	var buf bytes.Buffer
+
+	b := NewWithOpts(WithDimensions(1, 4), WithFormat(":count"), WithWriter(&buf), WithTTY(true))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b.Watch(ctx)
+	done := b.done
+
+	b.Tick()
+	b.Done()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("finishing the bar didn't stop it being watched")
+	}
+
+	if b.cancelled {
+		t.Error("finishing a watched bar marked it as cancelled")
+	}
+}
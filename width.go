@@ -0,0 +1,212 @@
+package bar
+
+// terminals display some characters (such as CJK ideographs and most emoji)
+// across two columns and others (such as combining accents) in none at all,
+// so measuring a string by its bytes or runes isn't enough to align it
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wideRanges are the inclusive ranges of runes that occupy two columns,
+// following the East Asian Width property (wide and fullwidth characters)
+// and the emoji that are presented as wide by default
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},
+	{0x231A, 0x231B},
+	{0x2329, 0x232A},
+	{0x23E9, 0x23EC},
+	{0x23F0, 0x23F0},
+	{0x23F3, 0x23F3},
+	{0x25FD, 0x25FE},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267F, 0x267F},
+	{0x2693, 0x2693},
+	{0x26A1, 0x26A1},
+	{0x26AA, 0x26AB},
+	{0x26BD, 0x26BE},
+	{0x26C4, 0x26C5},
+	{0x26CE, 0x26CE},
+	{0x26D4, 0x26D4},
+	{0x26EA, 0x26EA},
+	{0x26F2, 0x26F3},
+	{0x26F5, 0x26F5},
+	{0x26FA, 0x26FA},
+	{0x26FD, 0x26FD},
+	{0x2705, 0x2705},
+	{0x270A, 0x270B},
+	{0x2728, 0x2728},
+	{0x274C, 0x274C},
+	{0x274E, 0x274E},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27B0, 0x27B0},
+	{0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C},
+	{0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xA960, 0xA97F},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x16FE0, 0x16FE4},
+	{0x17000, 0x18AFF},
+	{0x1B000, 0x1B2FF},
+	{0x1F004, 0x1F004},
+	{0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E},
+	{0x1F191, 0x1F19A},
+	{0x1F200, 0x1F251},
+	{0x1F300, 0x1F320},
+	{0x1F32D, 0x1F335},
+	{0x1F337, 0x1F37C},
+	{0x1F37E, 0x1F393},
+	{0x1F3A0, 0x1F3CA},
+	{0x1F3CF, 0x1F3D3},
+	{0x1F3E0, 0x1F3F0},
+	{0x1F3F4, 0x1F3F4},
+	{0x1F3F8, 0x1F43E},
+	{0x1F440, 0x1F440},
+	{0x1F442, 0x1F4FC},
+	{0x1F4FF, 0x1F53D},
+	{0x1F54B, 0x1F54E},
+	{0x1F550, 0x1F567},
+	{0x1F57A, 0x1F57A},
+	{0x1F595, 0x1F596},
+	{0x1F5A4, 0x1F5A4},
+	{0x1F5FB, 0x1F64F},
+	{0x1F680, 0x1F6C5},
+	{0x1F6CC, 0x1F6CC},
+	{0x1F6D0, 0x1F6D2},
+	{0x1F6D5, 0x1F6D7},
+	{0x1F6EB, 0x1F6EC},
+	{0x1F6F4, 0x1F6FC},
+	{0x1F7E0, 0x1F7EB},
+	{0x1F90C, 0x1F93A},
+	{0x1F93C, 0x1F945},
+	{0x1F947, 0x1F9FF},
+	{0x1FA70, 0x1FAFF},
+	{0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+// runeWidth returns the number of columns r occupies when printed
+func runeWidth(r rune) int {
+	if r == 0 || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Cf, r) {
+		return 0
+	}
+
+	if unicode.IsControl(r) {
+		return 0
+	}
+
+	i := sort.Search(len(wideRanges), func(i int) bool {
+		return wideRanges[i][1] >= r
+	})
+	if i < len(wideRanges) && wideRanges[i][0] <= r {
+		return 2
+	}
+
+	return 1
+}
+
+// escapeLength returns the length in bytes of the ANSI escape sequence
+// (such as a color) at the start of s, or 0 if s doesn't start with one
+func escapeLength(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' || s[1] != '[' {
+		return 0
+	}
+
+	// a control sequence ends with its first byte in the range @ through ~
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7E {
+			return i + 1
+		}
+	}
+
+	return len(s)
+}
+
+// displayWidth returns the number of columns s occupies when printed,
+// accounting for wide and zero-width runes and ignoring escape sequences
+func displayWidth(s string) int {
+	width := 0
+
+	for len(s) > 0 {
+		if n := escapeLength(s); n > 0 {
+			s = s[n:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		width += runeWidth(r)
+		s = s[size:]
+	}
+
+	return width
+}
+
+// padLeft right-aligns s in a field of cols columns by adding spaces before
+// it; s is returned as is if it's already at least that wide
+func padLeft(s string, cols int) string {
+	if pad := cols - displayWidth(s); pad > 0 {
+		return strings.Repeat(" ", pad) + s
+	}
+
+	return s
+}
+
+// truncate cuts s down to at most cols columns, ending it with ellipsis if
+// anything was removed. Wide runes and escape sequences are never split; if
+// s contains any escape sequences, the color is reset before the ellipsis.
+func truncate(s string, cols int, ellipsis string) string {
+	if displayWidth(s) <= cols {
+		return s
+	}
+
+	limit := cols - displayWidth(ellipsis)
+	if limit < 0 {
+		limit, ellipsis = cols, ""
+	}
+
+	var sb strings.Builder
+	width, escaped := 0, false
+
+	for len(s) > 0 {
+		if n := escapeLength(s); n > 0 {
+			sb.WriteString(s[:n])
+			s = s[n:]
+			escaped = true
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		if width+runeWidth(r) > limit {
+			break
+		}
+
+		sb.WriteString(s[:size])
+		width += runeWidth(r)
+		s = s[size:]
+	}
+
+	if escaped {
+		sb.WriteString(resetColor)
+	}
+
+	sb.WriteString(ellipsis)
+	return sb.String()
+}
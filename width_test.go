@@ -0,0 +1,116 @@
+package bar
+
+import (
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	var testCases = []struct {
+		s        string
+		expected int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"█▏", 2},
+		{"不与", 4},
+		{"下载 file", 9},
+		{"한국어", 6},
+		{"ｆｕｌｌ", 8},
+		{"🎉", 2},
+		{"done 🚀", 7},
+		{"e\u0301", 1},
+		{"cafe\u0301 不", 7},
+		{"\u200b", 0},
+		{"\x1b[32mok\x1b[0m", 2},
+		{"\x1b[38;2;10;20;30m不\x1b[0m!", 3},
+		{"\t", 0},
+	}
+
+	for i, testCase := range testCases {
+		if got := displayWidth(testCase.s); got != testCase.expected {
+			t.Errorf("[%d] displayWidth(%#v)\n\n  got %d\n  want %d", i, testCase.s, got, testCase.expected)
+		}
+	}
+}
+
+func TestFitWidthWithWideRunes(t *testing.T) {
+	b := newTestBar(
+		newFakeClock(),
+		WithDimensions(20, 10),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithFormat("下载 :bar :file"),
+		WithContext(Context{Ctx("file", "🎉.zip")}),
+		WithFitWidth(),
+	)
+	b.progress = 10
+	b.termWidth = func() (int, bool) {
+		return 20, true
+	}
+
+	got := b.Render()
+	if want := "下载 [==>   ] 🎉.zip"; got != want {
+		t.Errorf("fitted render\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if width := displayWidth(got); width != 20 {
+		t.Errorf("fitted render is %d columns wide, want 20", width)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	var testCases = []struct {
+		s        string
+		cols     int
+		ellipsis string
+		expected string
+	}{
+		{"hello", 5, "…", "hello"},
+		{"hello world", 5, "", "hello"},
+		{"hello world", 5, "…", "hell…"},
+		{"hello world", 5, "...", "he..."},
+		{"hello", 2, "...", "he"},
+		{"不与不与", 5, "", "不与"},
+		{"不与不与", 5, "…", "不与…"},
+		{"ab不与", 3, "", "ab"},
+		{"done 🚀🚀", 6, "", "done "},
+		{"cafés", 4, "", "café"},
+		{"\x1b[32mgreen\x1b[0m text", 3, "", "\x1b[32mgre" + resetColor},
+		{"\x1b[32mgreen\x1b[0m text", 7, "…", "\x1b[32mgreen\x1b[0m " + resetColor + "…"},
+	}
+
+	for i, testCase := range testCases {
+		got := truncate(testCase.s, testCase.cols, testCase.ellipsis)
+		if got != testCase.expected {
+			t.Errorf(
+				"[%d] truncate(%#v, %d, %#v)\n\n  got %#v\n  want %#v",
+				i,
+				testCase.s,
+				testCase.cols,
+				testCase.ellipsis,
+				got,
+				testCase.expected,
+			)
+		}
+	}
+}
+
+func TestWithMaxWidth(t *testing.T) {
+	b := newTestBar(
+		newFakeClock(),
+		WithDimensions(20, 10),
+		WithDisplay("[", "=", ">", " ", "]"),
+		WithFormat(":bar :file"),
+		WithContext(Context{Ctx("file", "a-very-long-file-name-不与.zip")}),
+		WithMaxWidth(30, "…"),
+	)
+	b.progress = 10
+
+	got := b.Render()
+	if want := "[====>     ] a-very-long-file…"; got != want {
+		t.Errorf("truncated render\n\n  got %#v\n  want %#v", got, want)
+	}
+
+	if width := displayWidth(got); width > 30 {
+		t.Errorf("truncated render is %d columns wide, want at most 30", width)
+	}
+}
@@ -0,0 +1,130 @@
+package bar
+
+import (
+	"time"
+)
+
+// windowSamples is the number of samples kept to measure the rate over a
+// window; a new sample is only taken once a fraction of the window has
+// passed since the last, so that the samples always span the whole window
+// no matter how often the bar is updated
+const windowSamples = 32
+
+// rateSample is the bar's progress (in items or bytes) at a point in time
+type rateSample struct {
+	at       time.Time
+	progress int64
+}
+
+// rateWindow is a ring buffer of the samples taken over the last window
+type rateWindow struct {
+	samples      [windowSamples]rateSample
+	first, count int
+}
+
+// oldest returns the earliest sample in the window
+func (w *rateWindow) oldest() rateSample {
+	return w.samples[w.first]
+}
+
+// newest returns the latest sample in the window
+func (w *rateWindow) newest() rateSample {
+	return w.samples[(w.first+w.count-1)%windowSamples]
+}
+
+// push adds s to the window, replacing the oldest sample if it's full
+func (w *rateWindow) push(s rateSample) {
+	if w.count == windowSamples {
+		w.drop()
+	}
+
+	w.samples[(w.first+w.count)%windowSamples] = s
+	w.count++
+}
+
+// drop removes the oldest sample from the window
+func (w *rateWindow) drop() {
+	w.first = (w.first + 1) % windowSamples
+	w.count--
+}
+
+// measure adds s to the window and returns the rate since the start of the
+// last span, or false if no time has passed to measure it over. The earliest
+// sample kept is the last one taken before the span started, so that the
+// rate covers all of it; base is the sample to start from when the window is
+// empty.
+func (w *rateWindow) measure(base, s rateSample, span time.Duration) (float64, bool) {
+	if w.count == 0 {
+		w.push(base)
+	}
+
+	start := s.at.Add(-span)
+	for w.count > 1 && !w.samples[(w.first+1)%windowSamples].at.After(start) {
+		w.drop()
+	}
+
+	if s.at.Sub(w.newest().at) >= span/windowSamples {
+		w.push(s)
+	}
+
+	baseline := w.oldest()
+	seconds := s.at.Sub(baseline.at).Seconds()
+	if seconds <= 0 {
+		return 0, false
+	}
+
+	return float64(s.progress-baseline.progress) / seconds, true
+}
+
+// rateAverage is an exponentially weighted moving average of a rate. The
+// progress made and the time taken are averaged separately so that the rate
+// isn't skewed by uneven gaps between samples.
+type rateAverage struct {
+	sampledAt time.Time
+	sampled   int64
+	progress  float64
+	seconds   float64
+}
+
+// sample folds the progress made since the last sample (or since start, for
+// the first) into the average, weighting it by factor, and reports whether
+// it did; samples taken at the same instant as the last are folded into the
+// next one
+func (a *rateAverage) sample(start, now time.Time, progress int64, factor float64) bool {
+	if a.sampledAt.IsZero() {
+		a.sampledAt = start
+	}
+
+	seconds := now.Sub(a.sampledAt).Seconds()
+	if seconds <= 0 {
+		return false
+	}
+
+	a.progress += factor * (float64(progress-a.sampled) - a.progress)
+	a.seconds += factor * (seconds - a.seconds)
+	a.sampledAt, a.sampled = now, progress
+
+	return true
+}
+
+// rate returns the averaged rate
+func (a *rateAverage) rate() float64 {
+	if a.seconds == 0 {
+		return 0
+	}
+
+	return a.progress / a.seconds
+}
+
+// sampleWindow records the bar's progress at now and recomputes its rate
+// over the last rateWindow, and the ETA from it. The caller must hold b.mu,
+// and must call sampleWindow before setting the bar's new progress.
+func (b *Bar) sampleWindow(now time.Time, progress int) {
+	base := rateSample{b.startedAt, int64(b.progress)}
+	if rate, ok := b.window.measure(base, rateSample{now, int64(progress)}, b.rateWindow); ok {
+		b.rate = rate
+	}
+
+	b.progress = progress
+	b.updateETA()
+}